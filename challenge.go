@@ -0,0 +1,98 @@
+package rcodezeroacme
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/libdns/libdns"
+)
+
+// JWKThumbprint computes the RFC 7638 thumbprint of a JSON Web Key given
+// as JSON: base64url(SHA-256) over the key's required members in
+// lexicographic order.
+func JWKThumbprint(jwk []byte) (string, error) {
+	var key map[string]any
+	if err := json.Unmarshal(jwk, &key); err != nil {
+		return "", fmt.Errorf("jwk: %w", err)
+	}
+
+	kty, _ := key["kty"].(string)
+	var members []string
+	switch kty {
+	case "EC":
+		members = []string{"crv", "kty", "x", "y"}
+	case "RSA":
+		members = []string{"e", "kty", "n"}
+	case "OKP":
+		members = []string{"crv", "kty", "x"}
+	case "oct":
+		members = []string{"k", "kty"}
+	default:
+		return "", fmt.Errorf("jwk: unsupported key type %q", kty)
+	}
+
+	// Members are already sorted; build the JSON by hand so the order is
+	// guaranteed and no whitespace is added.
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, m := range members {
+		v, ok := key[m].(string)
+		if !ok {
+			return "", fmt.Errorf("jwk: %s key is missing %q", kty, m)
+		}
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, _ := json.Marshal(m)
+		value, _ := json.Marshal(v)
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+
+	sum := sha256.Sum256(buf.Bytes())
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// KeyAuthorization returns the ACME key authorization for a challenge
+// token: "<token>.<thumbprint>" (RFC 8555 section 8.1).
+func KeyAuthorization(token, thumbprint string) string {
+	return token + "." + thumbprint
+}
+
+// DNS01Value returns the TXT value for a dns-01 challenge:
+// base64url(SHA-256(keyAuthorization)) (RFC 8555 section 8.4).
+func DNS01Value(keyAuthorization string) string {
+	sum := sha256.Sum256([]byte(keyAuthorization))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// ChallengeName returns the dns-01 record name for domain relative to zone.
+// A leading "*." of a wildcard domain is dropped, so "example.com" and
+// "*.example.com" both map to "_acme-challenge" in zone "example.com".
+func ChallengeName(zone, domain string) string {
+	return relativeToZone(acmeChallengeFQDN(domain), zone)
+}
+
+// ChallengeTXT returns the dns-01 TXT record for domain, ready for
+// AppendRecords and DeleteRecords on zone; see ChallengeName.
+func ChallengeTXT(zone, domain, token, thumbprint string) libdns.TXT {
+	return libdns.TXT{
+		Name: ChallengeName(zone, domain),
+		Text: DNS01Value(KeyAuthorization(token, thumbprint)),
+	}
+}
+
+// ChallengeTXTFromJWK is ChallengeTXT for callers holding the account's
+// public JWK instead of its thumbprint.
+func ChallengeTXTFromJWK(zone, domain, token string, jwk []byte) (libdns.TXT, error) {
+	thumb, err := JWKThumbprint(jwk)
+	if err != nil {
+		return libdns.TXT{}, err
+	}
+	return ChallengeTXT(zone, domain, token, thumb), nil
+}
@@ -0,0 +1,38 @@
+package rcodezeroacme
+
+import "testing"
+
+func TestJWKThumbprint_RFC7638Example(t *testing.T) {
+	// RFC 7638 section 3.1.
+	jwk := []byte(`{
+		"kty": "RSA",
+		"n": "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw",
+		"e": "AQAB",
+		"alg": "RS256",
+		"kid": "2011-04-29"
+	}`)
+	got, err := JWKThumbprint(jwk)
+	if err != nil {
+		t.Fatalf("JWKThumbprint: %v", err)
+	}
+	if got != "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs" {
+		t.Errorf("thumbprint = %q", got)
+	}
+
+	if _, err := JWKThumbprint([]byte(`{"kty":"EC","crv":"P-256","x":"a"}`)); err == nil {
+		t.Errorf("expected error for incomplete EC key")
+	}
+}
+
+func TestChallengeTXT(t *testing.T) {
+	txt := ChallengeTXT("example.com.", "*.www.example.com", "token", "thumb")
+	if txt.Name != "_acme-challenge.www" {
+		t.Errorf("name = %q", txt.Name)
+	}
+	if len(txt.Text) != 43 || txt.Text != DNS01Value("token.thumb") {
+		t.Errorf("value = %q", txt.Text)
+	}
+	if apex := ChallengeTXT("example.com", "example.com", "token", "thumb"); apex.Name != "_acme-challenge" {
+		t.Errorf("apex name = %q", apex.Name)
+	}
+}
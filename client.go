@@ -5,30 +5,55 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
 	"strings"
 	"time"
+
+	"github.com/kagescode/libdns-rcodezeroacme/internal/transport"
 )
 
 const defaultBaseURL = "https://my.rcodezero.at"
 
-type HTTPClient interface {
-	Do(*http.Request) (*http.Response, error)
-}
+type HTTPClient = transport.HTTPClient
 
 type Client struct {
-	apiToken   string
+	tokens     TokenSource
 	baseURL    *url.URL
 	httpClient HTTPClient
 	timeout    time.Duration
+
+	// Retries is how often a failed GET is retried (network errors, 429,
+	// 502/503/504). Writes are never retried.
+	Retries int
+
+	// MaxResponseSize limits response bodies in bytes; 4 MiB if zero.
+	MaxResponseSize int64
+
+	// UserAgent replaces the default "libdns-rcodezeroacme/<version>".
+	// UserAgentSuffix is appended to it to identify the caller, e.g.
+	// "caddy/2.8".
+	UserAgent       string
+	UserAgentSuffix string
+
+	// Header holds extra headers sent with every request. Authorization
+	// and Content-Type cannot be overridden.
+	Header http.Header
 }
 
 func NewClient(apiToken, baseURL string, hc HTTPClient) (*Client, error) {
 	if strings.TrimSpace(apiToken) == "" {
 		return nil, fmt.Errorf("APIToken is required")
 	}
+	return NewClientWithTokenSource(StaticToken(apiToken), baseURL, hc)
+}
+
+// NewClientWithTokenSource is like NewClient but asks ts for the bearer
+// token on every request instead of using a fixed string.
+func NewClientWithTokenSource(ts TokenSource, baseURL string, hc HTTPClient) (*Client, error) {
+	if ts == nil {
+		return nil, fmt.Errorf("token source is required")
+	}
 	if baseURL == "" {
 		baseURL = defaultBaseURL
 	}
@@ -37,43 +62,32 @@ func NewClient(apiToken, baseURL string, hc HTTPClient) (*Client, error) {
 		return nil, fmt.Errorf("invalid base url: %w", err)
 	}
 	return &Client{
-		apiToken:   apiToken,
+		tokens:     ts,
 		baseURL:    u,
 		httpClient: hc,
 		timeout:    10 * time.Second,
 	}, nil
 }
 
-func (c *Client) do(req *http.Request, out any) error {
-	req.Header.Set("Authorization", "Bearer "+c.apiToken)
-	req.Header.Set("Accept", "application/json")
-
-	client := c.httpClient
-	if client == nil {
-		client = http.DefaultClient
-	}
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return fmt.Errorf("http do: %w", err)
-	}
-	defer func() { _ = resp.Body.Close() }()
-
-	raw, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return fmt.Errorf("read response: %w", err)
-	}
-
-	if resp.StatusCode/100 != 2 {
-		// Spec doesn’t document a structured ACME error payload; keep raw.
-		return fmt.Errorf("rcodezero acme http %d: %s", resp.StatusCode, string(raw))
-	}
+// WithRequestHeader returns a context whose API requests carry key: value
+// in addition to the client's headers, e.g. a per-operation request ID.
+func WithRequestHeader(ctx context.Context, key, value string) context.Context {
+	return transport.WithHeader(ctx, key, value)
+}
 
-	if out == nil {
-		return nil
+func (c *Client) do(req *http.Request, out any) error {
+	r := transport.Requester{
+		Token:           c.tokens.Token,
+		HTTP:            c.httpClient,
+		Retries:         c.Retries,
+		MaxResponseSize: c.MaxResponseSize,
+		UserAgent:       c.UserAgent,
+		UserAgentSuffix: c.UserAgentSuffix,
+		Header:          c.Header,
+		Prefix:          "rcodezero acme http",
 	}
-	if err := json.Unmarshal(raw, out); err != nil {
-		return fmt.Errorf("unmarshal: %w", err)
+	if err := r.Do(req, out); err != nil {
+		return err
 	}
 
 	// If out is *APIResponse, treat non-ok as error.
@@ -87,10 +101,14 @@ func (c *Client) do(req *http.Request, out any) error {
 }
 
 func (c *Client) GetRRsets(ctx context.Context, zone string, page, pageSize int) (*GetRRsetsResponse, error) {
-	zone = strings.TrimSuffix(strings.TrimSpace(zone), ".")
+	zone, err := trimZone(zone)
+	if err != nil {
+		return nil, err
+	}
 	if zone == "" {
 		return nil, fmt.Errorf("empty zone")
 	}
+	ctx = withZone(ctx, zone)
 
 	// /api/v1/acme/zones/{zone}/rrsets  (paginated) :contentReference[oaicite:6]{index=6}
 	endpoint := c.baseURL.JoinPath("api", "v1", "acme", "zones", zone, "rrsets")
@@ -116,10 +134,14 @@ func (c *Client) GetRRsets(ctx context.Context, zone string, page, pageSize int)
 }
 
 func (c *Client) PatchRRsets(ctx context.Context, zone string, sets []UpdateRRSet) (*APIResponse, error) {
-	zone = strings.TrimSuffix(strings.TrimSpace(zone), ".")
+	zone, err := trimZone(zone)
+	if err != nil {
+		return nil, err
+	}
 	if zone == "" {
 		return nil, fmt.Errorf("empty zone")
 	}
+	ctx = withZone(ctx, zone)
 
 	// /api/v1/acme/zones/{zone}/rrsets  PATCH :contentReference[oaicite:7]{index=7}
 	endpoint := c.baseURL.JoinPath("api", "v1", "acme", "zones", zone, "rrsets")
@@ -141,3 +163,63 @@ func (c *Client) PatchRRsets(ctx context.Context, zone string, sets []UpdateRRSe
 	}
 	return &out, nil
 }
+
+func (c *Client) GetZones(ctx context.Context, page, pageSize int) (*GetZonesResponse, error) {
+	// /api/v1/zones  (paginated)
+	endpoint := c.baseURL.JoinPath("api", "v1", "zones")
+	q := endpoint.Query()
+	if page > 0 {
+		q.Set("page", fmt.Sprintf("%d", page))
+	}
+	if pageSize > 0 {
+		q.Set("page_size", fmt.Sprintf("%d", pageSize))
+	}
+	endpoint.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var out GetZonesResponse
+	if err := c.do(req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetZone returns the zone's details, including serial and DNSSEC state.
+func (c *Client) GetZone(ctx context.Context, zone string) (*ZoneDetail, error) {
+	zone, err := trimZone(zone)
+	if err != nil {
+		return nil, err
+	}
+	if zone == "" {
+		return nil, fmt.Errorf("empty zone")
+	}
+	ctx = withZone(ctx, zone)
+
+	// /api/v1/zones/{zone}
+	endpoint := c.baseURL.JoinPath("api", "v1", "zones", zone)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var out ZoneDetail
+	if err := c.do(req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetDNSSEC returns the zone's DNSSEC state.
+func (c *Client) GetDNSSEC(ctx context.Context, zone string) (*DNSSECStatus, error) {
+	z, err := c.GetZone(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+	st := dnssecStatus(z)
+	return &st, nil
+}
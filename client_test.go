@@ -0,0 +1,147 @@
+package rcodezeroacme
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/libdns/libdns"
+
+	"github.com/kagescode/libdns-rcodezeroacme/internal/apitest"
+)
+
+func TestClient_NonJSONResponse(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		decodeErr   bool
+	}{
+		{"html page", "text/html; charset=utf-8", "<html><body><h1>Down for maintenance</h1></body></html>", false},
+		{"no content type", "", "Down for maintenance", true},
+		{"broken json", "application/json", `{"data":[`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hc := fakeHTTP(func(*http.Request) (*http.Response, error) {
+				resp := apitest.JSONResponse(200, tt.body)
+				resp.Header.Set("Content-Type", tt.contentType)
+				return resp, nil
+			})
+			c, _ := NewClient(apitest.Token, "", hc)
+
+			_, err := c.GetRRsets(context.Background(), "example.com", 1, 1)
+			var nj *NonJSONError
+			if !errors.As(err, &nj) {
+				t.Fatalf("err = %v, want *NonJSONError", err)
+			}
+			if nj.StatusCode != 200 || nj.ContentType != tt.contentType || (nj.Err != nil) != tt.decodeErr {
+				t.Errorf("NonJSONError = %+v", nj)
+			}
+			if !strings.Contains(nj.Excerpt, "maintenance") && tt.name != "broken json" {
+				t.Errorf("excerpt = %q", nj.Excerpt)
+			}
+		})
+	}
+}
+
+func TestClient_JSONContentTypeVariants(t *testing.T) {
+	for _, ct := range []string{"application/json", "application/json; charset=utf-8", "application/problem+json"} {
+		hc := fakeHTTP(func(*http.Request) (*http.Response, error) {
+			resp := apitest.JSONResponse(200, `{"data":[],"last_page":1}`)
+			resp.Header.Set("Content-Type", ct)
+			return resp, nil
+		})
+		c, _ := NewClient(apitest.Token, "", hc)
+		if _, err := c.GetRRsets(context.Background(), "example.com", 1, 1); err != nil {
+			t.Errorf("Content-Type %q: %v", ct, err)
+		}
+	}
+}
+
+func TestClient_MaxResponseSize(t *testing.T) {
+	big := `{"data":[],"pad":"` + strings.Repeat("x", 2000) + `"}`
+	hc := fakeHTTP(func(*http.Request) (*http.Response, error) {
+		resp := apitest.JSONResponse(200, big)
+		resp.ContentLength = -1 // unknown, as with chunked encoding
+		return resp, nil
+	})
+	c, _ := NewClient(apitest.Token, "", hc)
+	c.MaxResponseSize = 1024
+
+	if _, err := c.GetRRsets(context.Background(), "example.com", 1, 1); !errors.Is(err, ErrResponseTooLarge) {
+		t.Fatalf("err = %v, want ErrResponseTooLarge", err)
+	}
+
+	c.MaxResponseSize = 4096
+	if _, err := c.GetRRsets(context.Background(), "example.com", 1, 1); err != nil {
+		t.Fatalf("within limit: %v", err)
+	}
+}
+
+func TestClient_OversizedErrorKeepsStatus(t *testing.T) {
+	hc := fakeHTTP(func(*http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: 404,
+			Header:     http.Header{"Content-Type": []string{"text/html"}},
+			Body:       io.NopCloser(strings.NewReader("<html>" + strings.Repeat("not found ", 1000) + "</html>")),
+		}, nil
+	})
+	c, _ := NewClient(apitest.Token, "", hc)
+	c.MaxResponseSize = 256
+
+	_, err := c.GetRRsets(context.Background(), "example.com", 1, 1)
+	if !errors.Is(err, ErrZoneNotFound) {
+		t.Fatalf("err = %v, want ErrZoneNotFound", err)
+	}
+}
+
+func TestClient_UserAgentAndHeaders(t *testing.T) {
+	var got http.Header
+	hc := fakeHTTP(func(req *http.Request) (*http.Response, error) {
+		got = req.Header.Clone()
+		if req.Method == http.MethodPatch {
+			return apitest.JSONResponse(200, `{"status":"ok","message":""}`), nil
+		}
+		return apitest.JSONResponse(200, `{"data":[],"last_page":1}`), nil
+	})
+	p := &Provider{
+		APIToken:        apitest.Token,
+		HTTPClient:      hc,
+		UserAgentSuffix: "caddy/2.8",
+		Headers:         map[string]string{"X-Team": "infra", "Authorization": "Bearer other", "Content-Type": "text/plain"},
+	}
+	ctx := WithRequestHeader(context.Background(), "X-Request-ID", "req-1")
+	if _, err := p.GetRecords(ctx, "example.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	if ua := got.Get("User-Agent"); !strings.HasPrefix(ua, "libdns-rcodezeroacme/") || !strings.HasSuffix(ua, " caddy/2.8") {
+		t.Errorf("User-Agent = %q", ua)
+	}
+	if got.Get("X-Team") != "infra" || got.Get("X-Request-ID") != "req-1" {
+		t.Errorf("headers = %v", got)
+	}
+	if got.Get("Authorization") != "Bearer "+apitest.Token {
+		t.Errorf("Authorization overridden: %q", got.Get("Authorization"))
+	}
+
+	if _, err := p.AppendRecords(ctx, "example.com", []libdns.Record{libdns.TXT{Name: "_acme-challenge", Text: "v"}}); err != nil {
+		t.Fatal(err)
+	}
+	if ct := got.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type overridden: %q", ct)
+	}
+
+	c, _ := NewClient(apitest.Token, "", hc)
+	c.UserAgent = "custom/1"
+	if _, err := c.GetRRsets(context.Background(), "example.com", 1, 1); err != nil {
+		t.Fatal(err)
+	}
+	if ua := got.Get("User-Agent"); ua != "custom/1" {
+		t.Errorf("User-Agent = %q", ua)
+	}
+}
@@ -0,0 +1,50 @@
+package rcodezeroacme
+
+import (
+	"crypto/sha256"
+	"encoding/base32"
+	"strings"
+
+	"github.com/libdns/libdns"
+)
+
+// AccountLabel returns the DNS-ACCOUNT-01 label for an ACME account URL:
+// "_" followed by the first 10 characters of the lower-case base32 encoding
+// of SHA-256(accountURL).
+func AccountLabel(accountURL string) string {
+	sum := sha256.Sum256([]byte(accountURL))
+	enc := base32.StdEncoding.EncodeToString(sum[:])
+	return "_" + strings.ToLower(enc[:10])
+}
+
+// AccountChallengeName returns the absolute DNS-ACCOUNT-01 validation name
+// for domain, "_<label>._acme-challenge.<domain>.". A leading "*." of a
+// wildcard domain is dropped.
+func AccountChallengeName(accountURL, domain string) string {
+	return AccountLabel(accountURL) + "." + acmeChallengeFQDN(domain)
+}
+
+// AccountChallengeTXT returns the TXT record that presents value for the
+// DNS-ACCOUNT-01 challenge of domain, with its name relative to zone, ready
+// for AppendRecords and DeleteRecords. The provider's NamePolicy must have
+// AllowAccountLabels set.
+func AccountChallengeTXT(zone, domain, accountURL, value string) libdns.TXT {
+	return libdns.TXT{
+		Name: relativeToZone(AccountChallengeName(accountURL, domain), zone),
+		Text: value,
+	}
+}
+
+// acmeChallengeFQDN returns "_acme-challenge.<domain>." with a leading
+// wildcard label removed.
+func acmeChallengeFQDN(domain string) string {
+	d := strings.TrimSuffix(strings.TrimSpace(domain), ".")
+	d = strings.TrimPrefix(d, "*.")
+	return "_acme-challenge." + d + "."
+}
+
+// relativeToZone returns fqdn relative to zone; both may carry a trailing dot.
+func relativeToZone(fqdn, zone string) string {
+	z := strings.TrimSuffix(strings.TrimSpace(zone), ".") + "."
+	return libdns.RelativeName(strings.TrimSuffix(fqdn, ".")+".", z)
+}
@@ -0,0 +1,57 @@
+package rcodezeroacme
+
+import (
+	"context"
+	"testing"
+
+	"github.com/libdns/libdns"
+
+	"github.com/kagescode/libdns-rcodezeroacme/internal/apitest"
+)
+
+func TestAccountLabel(t *testing.T) {
+	acct := "https://example.com/acme/acct/ExampleAccount"
+	label := AccountLabel(acct)
+	if !isAccountLabel(label) {
+		t.Fatalf("AccountLabel(%q) = %q is not a valid account label", acct, label)
+	}
+	if AccountLabel(acct) != label || AccountLabel(acct+"2") == label {
+		t.Fatalf("label must be deterministic and account-specific")
+	}
+
+	name := AccountChallengeName(acct, "*.www.example.com")
+	if name != label+"._acme-challenge.www.example.com." {
+		t.Errorf("AccountChallengeName = %q", name)
+	}
+	if rel := AccountChallengeTXT("example.com.", "www.example.com", acct, "v").Name; rel != label+"._acme-challenge.www" {
+		t.Errorf("relative name = %q", rel)
+	}
+}
+
+func TestAccountChallenge_NoCollisionBetweenAccounts(t *testing.T) {
+	api := newFakeACME()
+	p := &Provider{APIToken: apitest.Token, HTTPClient: api, NamePolicy: NamePolicy{AllowAccountLabels: true}}
+	ctx := context.Background()
+
+	a := AccountChallengeTXT("example.com", "example.com", "https://ca.example/acct/1", "value-a")
+	b := AccountChallengeTXT("example.com", "example.com", "https://ca.example/acct/2", "value-b")
+
+	if _, err := p.AppendRecords(ctx, "example.com.", []libdns.Record{a, b}); err != nil {
+		t.Fatalf("AppendRecords: %v", err)
+	}
+	if _, err := p.DeleteRecords(ctx, "example.com.", []libdns.Record{a}); err != nil {
+		t.Fatalf("DeleteRecords: %v", err)
+	}
+
+	if got := api.values(a.Name + ".example.com."); len(got) != 0 {
+		t.Errorf("account 1 values left: %q", got)
+	}
+	if got := api.values(b.Name + ".example.com."); len(got) != 1 || got[0] != "value-b" {
+		t.Errorf("account 2 values = %q", got)
+	}
+
+	strict := &Provider{APIToken: apitest.Token, HTTPClient: api}
+	if _, err := strict.AppendRecords(ctx, "example.com.", []libdns.Record{a}); err == nil {
+		t.Errorf("default policy must reject account labels")
+	}
+}
@@ -0,0 +1,150 @@
+package rcodezeroacme
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrDNSSECBroken is wrapped by Diagnose errors for zones whose signing is
+// broken, as opposed to zones the provider cannot reach.
+var ErrDNSSECBroken = errors.New("zone DNSSEC is broken")
+
+// DNSSECState is a zone's signing state.
+type DNSSECState string
+
+const (
+	DNSSECUnsigned DNSSECState = "unsigned"
+	DNSSECSigned   DNSSECState = "signed"
+	DNSSECFailed   DNSSECState = "failed"
+	// DNSSECUnknown is reported for values the API documents no meaning
+	// for; check the raw Status and KSKStatus.
+	DNSSECUnknown DNSSECState = "unknown"
+)
+
+// DNSSECStatus is a zone's DNSSEC state as reported by the API.
+type DNSSECStatus struct {
+	// State is the signing state. It is DNSSECUnknown if Status or, for a
+	// signed zone, KSKStatus has a value not listed below.
+	State DNSSECState
+
+	// Signed reports whether RcodeZero signs the zone.
+	Signed bool
+
+	// DSPublished reports whether the DS record is in place at the parent.
+	// Only meaningful for signed zones.
+	DSPublished bool
+
+	// KeyRollover reports a KSK rollover in progress.
+	KeyRollover bool
+
+	// DS lists the DS records the parent should publish.
+	DS []string
+
+	// Status, KSKStatus and Detail are the raw values reported by the API.
+	Status    string
+	KSKStatus string
+	Detail    string
+}
+
+// Problem describes what is wrong with the zone's signing, or returns ""
+// if nothing is. Unsigned zones, rollovers and unknown states are not
+// problems.
+func (s DNSSECStatus) Problem() string {
+	switch {
+	case s.State == DNSSECFailed:
+		return fmt.Sprintf("signing failed: %s", firstNonEmpty(s.Detail, s.Status))
+	case s.State == DNSSECSigned && !s.DSPublished:
+		return "zone is signed but the DS record is not published at the parent"
+	}
+	return ""
+}
+
+// dnssecStates maps the API's dnssec_status values.
+var dnssecStates = map[string]DNSSECState{
+	"no":       DNSSECUnsigned,
+	"unsigned": DNSSECUnsigned,
+	"yes":      DNSSECSigned,
+	"signed":   DNSSECSigned,
+	"failed":   DNSSECFailed,
+	"error":    DNSSECFailed,
+}
+
+// kskStates maps the API's dnssec_ksk_status values of signed zones.
+var kskStates = map[string]struct{ dsPublished, rollover bool }{
+	"active":                   {dsPublished: true},
+	"ds published":             {dsPublished: true},
+	"waiting for ds":           {},
+	"ds missing":               {},
+	"ksk rollover in progress": {dsPublished: true, rollover: true},
+}
+
+func dnssecStatus(z *ZoneDetail) DNSSECStatus {
+	st := DNSSECStatus{
+		Status:    z.DNSSECStatus,
+		KSKStatus: z.DNSSECKSKStatus,
+		Detail:    firstNonEmpty(z.DNSSECStatusDetail, z.DNSSECKSKStatusDetail),
+		DS:        z.DNSSECDS,
+	}
+
+	state, ok := dnssecStates[strings.ToLower(strings.TrimSpace(z.DNSSECStatus))]
+	if !ok {
+		st.State = DNSSECUnknown
+		return st
+	}
+	st.State = state
+	st.Signed = state == DNSSECSigned
+	if !st.Signed {
+		return st
+	}
+
+	ksk, ok := kskStates[strings.ToLower(strings.TrimSpace(z.DNSSECKSKStatus))]
+	if !ok {
+		st.State = DNSSECUnknown
+		return st
+	}
+	st.DSPublished = ksk.dsPublished
+	st.KeyRollover = ksk.rollover
+	return st
+}
+
+// Diagnose is Verify plus each reachable zone's DNSSEC state, so that a
+// failing validation can be told apart as "provider failed" (Status, Err)
+// or "zone signing broken" (DNSSEC, DNSSECErr).
+//
+// The DNSSEC state is read from the zone endpoint, which ACME-only tokens
+// may not be allowed to access; such failures are reported in DNSSECErr
+// but don't fail Diagnose. The returned error joins provider failures and
+// broken zones; the latter match ErrDNSSECBroken.
+func (p *Provider) Diagnose(ctx context.Context, zones ...string) ([]ZoneReport, error) {
+	reports, err := p.Verify(ctx, zones...)
+	if reports == nil {
+		return nil, err
+	}
+	errs := []error{err}
+
+	for i := range reports {
+		r := &reports[i]
+		if r.Status != VerifyOK {
+			continue
+		}
+		r.DNSSEC, r.DNSSECErr = p.client.GetDNSSEC(ctx, r.Zone)
+		if r.DNSSEC == nil {
+			continue
+		}
+		if problem := r.DNSSEC.Problem(); problem != "" {
+			errs = append(errs, fmt.Errorf("zone %s: %w: %s", r.Zone, ErrDNSSECBroken, problem))
+		}
+	}
+	return reports, errors.Join(errs...)
+}
+
+func firstNonEmpty(vals ...string) string {
+	for _, v := range vals {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
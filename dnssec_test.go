@@ -0,0 +1,77 @@
+package rcodezeroacme
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/kagescode/libdns-rcodezeroacme/internal/apitest"
+)
+
+func TestDNSSECStatus(t *testing.T) {
+	tests := []struct {
+		zone                          ZoneDetail
+		state                         DNSSECState
+		signed, dsPublished, rollover bool
+		problem                       bool
+	}{
+		{zone: ZoneDetail{DNSSECStatus: "no"}, state: DNSSECUnsigned},
+		{zone: ZoneDetail{DNSSECStatus: "yes", DNSSECKSKStatus: "active"}, state: DNSSECSigned, signed: true, dsPublished: true},
+		{zone: ZoneDetail{DNSSECStatus: "yes", DNSSECKSKStatus: "waiting for DS"}, state: DNSSECSigned, signed: true, problem: true},
+		{zone: ZoneDetail{DNSSECStatus: "yes", DNSSECKSKStatus: "KSK rollover in progress"}, state: DNSSECSigned, signed: true, dsPublished: true, rollover: true},
+		{zone: ZoneDetail{DNSSECStatus: "failed", DNSSECStatusDetail: "signer unreachable"}, state: DNSSECFailed, problem: true},
+		{zone: ZoneDetail{DNSSECStatus: "partially"}, state: DNSSECUnknown},
+		{zone: ZoneDetail{}, state: DNSSECUnknown},
+		{zone: ZoneDetail{DNSSECStatus: "yes", DNSSECKSKStatus: "pending"}, state: DNSSECUnknown, signed: true},
+	}
+	for _, tt := range tests {
+		st := dnssecStatus(&tt.zone)
+		if st.State != tt.state || st.Signed != tt.signed || st.DSPublished != tt.dsPublished || st.KeyRollover != tt.rollover || (st.Problem() != "") != tt.problem {
+			t.Errorf("dnssecStatus(%+v) = %+v (problem %q)", tt.zone, st, st.Problem())
+		}
+	}
+}
+
+func TestDiagnose_SeparatesProviderAndSigningFailures(t *testing.T) {
+	hc := fakeHTTP(func(req *http.Request) (*http.Response, error) {
+		switch {
+		case strings.Contains(req.URL.Path, "/denied.example"):
+			return apitest.JSONResponse(403, `{"status":"failed","message":"forbidden"}`), nil
+		case strings.Contains(req.URL.Path, "/acme/"):
+			return apitest.JSONResponse(200, `{"data":[],"last_page":1}`), nil
+		case strings.HasSuffix(req.URL.Path, "/zones/good.example"):
+			return apitest.JSONResponse(200, `{"domain":"good.example","dnssec_status":"yes","dnssec_ksk_status":"active","dnssec_ds":["good.example. IN DS 1 13 2 AB"]}`), nil
+		case strings.HasSuffix(req.URL.Path, "/zones/nods.example"):
+			return apitest.JSONResponse(200, `{"domain":"nods.example","dnssec_status":"yes","dnssec_ksk_status":"waiting for DS","dnssec_ds":"nods.example. IN DS 1 13 2 CD"}`), nil
+		}
+		return apitest.JSONResponse(403, `{"status":"failed","message":"forbidden"}`), nil
+	})
+
+	p := &Provider{APIToken: apitest.Token, HTTPClient: hc}
+	reports, err := p.Diagnose(context.Background(), "good.example", "nods.example", "denied.example", "acmeonly.example")
+	if !errors.Is(err, ErrDNSSECBroken) || !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("Diagnose error = %v", err)
+	}
+	if len(reports) != 4 {
+		t.Fatalf("got %d reports", len(reports))
+	}
+
+	good, nods, denied, acmeOnly := reports[0], reports[1], reports[2], reports[3]
+	if good.DNSSEC == nil || good.DNSSEC.Problem() != "" || len(good.DNSSEC.DS) != 1 {
+		t.Errorf("good: %+v", good.DNSSEC)
+	}
+	if nods.DNSSEC == nil || nods.DNSSEC.DSPublished || len(nods.DNSSEC.DS) != 1 {
+		t.Errorf("nods: %+v", nods.DNSSEC)
+	}
+	if denied.Status != VerifyUnauthorized || denied.DNSSEC != nil {
+		t.Errorf("denied: %+v", denied)
+	}
+	if acmeOnly.Status != VerifyOK || acmeOnly.DNSSEC != nil || !errors.Is(acmeOnly.DNSSECErr, ErrUnauthorized) {
+		t.Errorf("acmeonly: %+v", acmeOnly)
+	}
+	if strings.Contains(err.Error(), "acmeonly") {
+		t.Errorf("unreadable DNSSEC state should not fail Diagnose: %v", err)
+	}
+}
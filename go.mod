@@ -2,4 +2,9 @@ module github.com/kagescode/libdns-rcodezeroacme
 
 go 1.22
 
-require github.com/libdns/libdns v1.0.0
+require (
+	github.com/libdns/libdns v1.0.0
+	golang.org/x/net v0.35.0
+)
+
+require golang.org/x/text v0.22.0 // indirect
@@ -0,0 +1,15 @@
+package rcodezeroacme
+
+import "github.com/kagescode/libdns-rcodezeroacme/internal/idn"
+
+// trimZone returns zone without surrounding space and trailing dot, with
+// internationalized labels converted to their ASCII (punycode) form.
+func trimZone(zone string) (string, error) { return idn.TrimZone(zone) }
+
+// toASCIIName converts each non-ASCII label of name to punycode; see
+// idn.ToASCII.
+func toASCIIName(name string) (string, error) { return idn.ToASCII(name) }
+
+// toUnicodeName converts punycode labels of name back to Unicode; see
+// idn.ToUnicode.
+func toUnicodeName(name string) string { return idn.ToUnicode(name) }
@@ -0,0 +1,51 @@
+package rcodezeroacme
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+
+	"github.com/kagescode/libdns-rcodezeroacme/internal/apitest"
+)
+
+func TestIDNA_ZoneAndNameConversion(t *testing.T) {
+	zone, err := trimZone(" Bücher.example. ")
+	if err != nil || zone != "xn--bcher-kva.example" {
+		t.Fatalf("trimZone = %q, %v", zone, err)
+	}
+
+	fqdn, _, _, err := ensureAcmeTXT(zone, libdns.TXT{Name: "_acme-challenge.straße", Text: "v"}, NamePolicy{})
+	if err != nil {
+		t.Fatalf("ensureAcmeTXT: %v", err)
+	}
+	if fqdn != "_acme-challenge.xn--strae-oqa.xn--bcher-kva.example." {
+		t.Fatalf("fqdn = %q", fqdn)
+	}
+	if got := toUnicodeName("_acme-challenge.xn--strae-oqa"); got != "_acme-challenge.straße" {
+		t.Errorf("toUnicodeName = %q", got)
+	}
+}
+
+func TestIDNA_GetRecordsUnicodeNames(t *testing.T) {
+	api := newFakeACME()
+	p := &Provider{APIToken: apitest.Token, HTTPClient: api, UnicodeNames: true}
+	ctx := context.Background()
+
+	rec := libdns.TXT{Name: "_acme-challenge.straße", Text: "v", TTL: time.Minute}
+	if _, err := p.AppendRecords(ctx, "bücher.example.", []libdns.Record{rec}); err != nil {
+		t.Fatalf("AppendRecords: %v", err)
+	}
+	if got := api.values("_acme-challenge.xn--strae-oqa.xn--bcher-kva.example."); len(got) != 1 {
+		t.Fatalf("record not stored under punycode name")
+	}
+
+	recs, err := p.GetRecords(ctx, "bücher.example.")
+	if err != nil || len(recs) != 1 {
+		t.Fatalf("GetRecords = %v, %v", recs, err)
+	}
+	if name := recs[0].RR().Name; name != "_acme-challenge.straße" {
+		t.Errorf("name = %q, want Unicode form", name)
+	}
+}
@@ -0,0 +1,22 @@
+// Package apitest holds fixtures shared by the tests of the ACME and v2
+// API packages.
+package apitest
+
+import (
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Token is a token of realistic length, so redaction of it in error
+// excerpts can't garble ordinary text.
+const Token = "rcz_test_7f3a9c1e5b2d4f60"
+
+// JSONResponse returns a response with status and body, declared as JSON.
+func JSONResponse(status int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+}
@@ -0,0 +1,194 @@
+// Package dnsquery sends the few raw DNS queries the provider needs and the
+// standard library resolver can't make.
+package dnsquery
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+const (
+	typeSOA = 6
+	classIN = 1
+
+	defaultTimeout = 5 * time.Second
+)
+
+// SOASerial asks server ("host" or "host:port") for the SOA of zone and
+// returns its serial. The query is non-recursive, so server should be
+// authoritative for the zone.
+func SOASerial(ctx context.Context, server, zone string) (uint32, error) {
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "53")
+	}
+	msg, id, err := buildQuery(zone, typeSOA)
+	if err != nil {
+		return 0, err
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp", server)
+	if err != nil {
+		return 0, fmt.Errorf("soa %s @%s: %w", zone, server, err)
+	}
+	defer conn.Close()
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(defaultTimeout)
+	}
+	_ = conn.SetDeadline(deadline)
+
+	if _, err := conn.Write(msg); err != nil {
+		return 0, fmt.Errorf("soa %s @%s: %w", zone, server, err)
+	}
+	buf := make([]byte, 4096)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return 0, fmt.Errorf("soa %s @%s: %w", zone, server, err)
+		}
+		serial, err := parseSOAResponse(buf[:n], id)
+		if errors.Is(err, errWrongID) {
+			continue // stray datagram
+		}
+		if err != nil {
+			return 0, fmt.Errorf("soa %s @%s: %w", zone, server, err)
+		}
+		return serial, nil
+	}
+}
+
+// Nameservers returns the authoritative servers of zone as "host:53",
+// looked up through the system resolver.
+func Nameservers(ctx context.Context, zone string) ([]string, error) {
+	nss, err := net.DefaultResolver.LookupNS(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]string, 0, len(nss))
+	for _, ns := range nss {
+		out = append(out, net.JoinHostPort(strings.TrimSuffix(ns.Host, "."), "53"))
+	}
+	return out, nil
+}
+
+// SerialAtLeast reports whether serial a is equal to or newer than b using
+// serial number arithmetic (RFC 1982), so wrap-around is handled.
+func SerialAtLeast(a, b uint32) bool {
+	return int32(a-b) >= 0
+}
+
+var errWrongID = errors.New("response id mismatch")
+
+func buildQuery(name string, qtype uint16) ([]byte, uint16, error) {
+	var idb [2]byte
+	if _, err := rand.Read(idb[:]); err != nil {
+		return nil, 0, err
+	}
+	id := binary.BigEndian.Uint16(idb[:])
+
+	msg := make([]byte, 12, 512)
+	binary.BigEndian.PutUint16(msg[0:], id)
+	binary.BigEndian.PutUint16(msg[4:], 1) // QDCOUNT
+
+	name = strings.TrimSuffix(name, ".")
+	if name != "" {
+		for _, label := range strings.Split(name, ".") {
+			if label == "" || len(label) > 63 {
+				return nil, 0, fmt.Errorf("invalid name %q", name)
+			}
+			msg = append(msg, byte(len(label)))
+			msg = append(msg, label...)
+		}
+	}
+	msg = append(msg, 0)
+	msg = binary.BigEndian.AppendUint16(msg, qtype)
+	msg = binary.BigEndian.AppendUint16(msg, classIN)
+	return msg, id, nil
+}
+
+func parseSOAResponse(msg []byte, id uint16) (uint32, error) {
+	if len(msg) < 12 {
+		return 0, fmt.Errorf("short response")
+	}
+	if binary.BigEndian.Uint16(msg[0:]) != id {
+		return 0, errWrongID
+	}
+	if msg[2]&0x80 == 0 {
+		return 0, fmt.Errorf("not a response")
+	}
+	if msg[2]&0x02 != 0 {
+		// A truncated answer may lack the SOA; TCP is not supported.
+		return 0, fmt.Errorf("truncated response")
+	}
+	if rcode := msg[3] & 0x0f; rcode != 0 {
+		return 0, fmt.Errorf("server returned rcode %d", rcode)
+	}
+	qd := int(binary.BigEndian.Uint16(msg[4:]))
+	an := int(binary.BigEndian.Uint16(msg[6:]))
+
+	off := 12
+	var err error
+	for i := 0; i < qd; i++ {
+		if off, err = skipName(msg, off); err != nil {
+			return 0, err
+		}
+		off += 4
+	}
+	for i := 0; i < an; i++ {
+		if off, err = skipName(msg, off); err != nil {
+			return 0, err
+		}
+		if off+10 > len(msg) {
+			return 0, fmt.Errorf("truncated answer")
+		}
+		rtype := binary.BigEndian.Uint16(msg[off:])
+		rdlen := int(binary.BigEndian.Uint16(msg[off+8:]))
+		off += 10
+		if off+rdlen > len(msg) {
+			return 0, fmt.Errorf("truncated answer")
+		}
+		if rtype == typeSOA {
+			p := off
+			if p, err = skipName(msg, p); err != nil { // MNAME
+				return 0, err
+			}
+			if p, err = skipName(msg, p); err != nil { // RNAME
+				return 0, err
+			}
+			if p+4 > off+rdlen {
+				return 0, fmt.Errorf("truncated SOA")
+			}
+			return binary.BigEndian.Uint32(msg[p:]), nil
+		}
+		off += rdlen
+	}
+	return 0, fmt.Errorf("no SOA in answer")
+}
+
+// skipName returns the offset just past the (possibly compressed) name at
+// off.
+func skipName(msg []byte, off int) (int, error) {
+	for {
+		if off >= len(msg) {
+			return 0, fmt.Errorf("truncated name")
+		}
+		l := int(msg[off])
+		switch {
+		case l == 0:
+			return off + 1, nil
+		case l&0xc0 == 0xc0:
+			return off + 2, nil
+		case l&0xc0 != 0:
+			return 0, fmt.Errorf("bad label type")
+		}
+		off += 1 + l
+	}
+}
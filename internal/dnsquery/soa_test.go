@@ -0,0 +1,92 @@
+package dnsquery
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+)
+
+// serveSOA answers one SOA query on a local UDP socket with serial.
+func serveSOA(t *testing.T, serial uint32) string {
+	t.Helper()
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("no local UDP: %v", err)
+	}
+	t.Cleanup(func() { pc.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		n, addr, err := pc.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		q := buf[:n]
+		resp := append([]byte(nil), q...)
+		resp[2] |= 0x84                         // QR, AA
+		binary.BigEndian.PutUint16(resp[6:], 1) // ANCOUNT
+
+		resp = append(resp, 0xc0, 12) // name: pointer to question
+		resp = binary.BigEndian.AppendUint16(resp, typeSOA)
+		resp = binary.BigEndian.AppendUint16(resp, classIN)
+		resp = binary.BigEndian.AppendUint32(resp, 3600)
+		rdata := []byte{3, 'n', 's', '1', 0xc0, 12, 0xc0, 12}
+		rdata = binary.BigEndian.AppendUint32(rdata, serial)
+		for i := 0; i < 4; i++ {
+			rdata = binary.BigEndian.AppendUint32(rdata, 3600)
+		}
+		resp = binary.BigEndian.AppendUint16(resp, uint16(len(rdata)))
+		resp = append(resp, rdata...)
+		pc.WriteTo(resp, addr)
+	}()
+	return pc.LocalAddr().String()
+}
+
+func TestSOASerial(t *testing.T) {
+	addr := serveSOA(t, 2026101801)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	got, err := SOASerial(ctx, addr, "example.com.")
+	if err != nil {
+		t.Fatalf("SOASerial: %v", err)
+	}
+	if got != 2026101801 {
+		t.Errorf("serial = %d", got)
+	}
+}
+
+func TestSerialAtLeast(t *testing.T) {
+	tests := []struct {
+		a, b uint32
+		want bool
+	}{
+		{5, 5, true},
+		{6, 5, true},
+		{4, 5, false},
+		{1, 0xfffffff0, true}, // wrapped
+		{0xfffffff0, 1, false},
+	}
+	for _, tt := range tests {
+		if got := SerialAtLeast(tt.a, tt.b); got != tt.want {
+			t.Errorf("SerialAtLeast(%d, %d) = %v", tt.a, tt.b, got)
+		}
+	}
+}
+
+func TestParseSOAResponse_RejectsQueriesAndTruncation(t *testing.T) {
+	msg, id, err := buildQuery("example.com.", typeSOA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parseSOAResponse(msg, id); err == nil || err.Error() != "not a response" {
+		t.Errorf("query echoed back: err = %v", err)
+	}
+
+	msg[2] |= 0x80 | 0x02 // QR, TC
+	if _, err := parseSOAResponse(msg, id); err == nil || err.Error() != "truncated response" {
+		t.Errorf("truncated response: err = %v", err)
+	}
+}
@@ -0,0 +1,61 @@
+// Package idn converts internationalized domain names between Unicode and
+// their ASCII (punycode) form, label by label.
+package idn
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// ToASCII converts each non-ASCII label of name to punycode ("bücher" ->
+// "xn--bcher-kva"). ASCII labels, including underscore labels such as
+// _acme-challenge, are left untouched. A trailing dot is preserved.
+func ToASCII(name string) (string, error) {
+	if isASCII(name) {
+		return name, nil
+	}
+	labels := strings.Split(name, ".")
+	for i, l := range labels {
+		if isASCII(l) {
+			continue
+		}
+		a, err := idna.Lookup.ToASCII(l)
+		if err != nil {
+			return "", fmt.Errorf("invalid internationalized name %q: %w", name, err)
+		}
+		labels[i] = a
+	}
+	return strings.Join(labels, "."), nil
+}
+
+// ToUnicode converts punycode labels of name back to Unicode. Labels that
+// fail to decode are left as they are.
+func ToUnicode(name string) string {
+	labels := strings.Split(name, ".")
+	for i, l := range labels {
+		if !strings.HasPrefix(strings.ToLower(l), "xn--") {
+			continue
+		}
+		if u, err := idna.Lookup.ToUnicode(l); err == nil {
+			labels[i] = u
+		}
+	}
+	return strings.Join(labels, ".")
+}
+
+// TrimZone returns zone without surrounding space and trailing dot, in
+// ASCII form.
+func TrimZone(zone string) (string, error) {
+	return ToASCII(strings.TrimSuffix(strings.TrimSpace(zone), "."))
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
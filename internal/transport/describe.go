@@ -0,0 +1,61 @@
+package transport
+
+import (
+	"fmt"
+	"log/slog"
+)
+
+// TypeName describes a token source by its type only; its value may hold
+// the token.
+func TypeName(v any) string {
+	if v == nil {
+		return "nil"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// ClientInfo is the token-free description of an API client, for its
+// String, GoString and LogValue methods.
+type ClientInfo struct {
+	Type    string // e.g. "rcodezeroacme.Client"
+	BaseURL string
+	Tokens  any
+	Retries int
+}
+
+func (i ClientInfo) String() string {
+	return fmt.Sprintf("%s{BaseURL:%q, TokenSource:%s, Retries:%d}", i.Type, i.BaseURL, TypeName(i.Tokens), i.Retries)
+}
+
+func (i ClientInfo) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("base_url", i.BaseURL),
+		slog.String("token_source", TypeName(i.Tokens)),
+		slog.Int("retries", i.Retries),
+	)
+}
+
+// ProviderInfo is the token-free description of the fields every provider
+// has. Providers with more fields add them to Fields and Attrs.
+type ProviderInfo struct {
+	BaseURL     string
+	APIToken    string
+	TokenSource any
+	Retries     int
+}
+
+// Fields formats the fields for a Go-syntax-like String.
+func (i ProviderInfo) Fields() string {
+	return fmt.Sprintf("BaseURL:%q, APIToken:%q, TokenSource:%s, Retries:%d",
+		i.BaseURL, RedactSecret(i.APIToken), TypeName(i.TokenSource), i.Retries)
+}
+
+// Attrs returns the fields for a LogValue group.
+func (i ProviderInfo) Attrs() []slog.Attr {
+	return []slog.Attr{
+		slog.String("base_url", i.BaseURL),
+		slog.String("api_token", RedactSecret(i.APIToken)),
+		slog.String("token_source", TypeName(i.TokenSource)),
+		slog.Int("retries", i.Retries),
+	}
+}
@@ -0,0 +1,105 @@
+package transport
+
+import (
+	"context"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"sync"
+)
+
+// ModulePath is the module reported in DefaultUserAgent.
+const ModulePath = "github.com/kagescode/libdns-rcodezeroacme"
+
+var (
+	defaultUserAgent     string
+	defaultUserAgentOnce sync.Once
+)
+
+// DefaultUserAgent is "libdns-rcodezeroacme/<version>", with the module
+// version taken from the build info ("devel" when built from a checkout).
+func DefaultUserAgent() string {
+	defaultUserAgentOnce.Do(func() {
+		defaultUserAgent = "libdns-rcodezeroacme/" + moduleVersion()
+	})
+	return defaultUserAgent
+}
+
+func moduleVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "devel"
+	}
+	if info.Main.Path == ModulePath && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	for _, dep := range info.Deps {
+		if dep.Path != ModulePath {
+			continue
+		}
+		if dep.Replace != nil && dep.Replace.Version != "" {
+			return dep.Replace.Version
+		}
+		if dep.Version != "" {
+			return dep.Version
+		}
+	}
+	return "devel"
+}
+
+func (r *Requester) userAgent() string {
+	ua := r.UserAgent
+	if ua == "" {
+		ua = DefaultUserAgent()
+	}
+	if suffix := strings.TrimSpace(r.UserAgentSuffix); suffix != "" {
+		ua += " " + suffix
+	}
+	return ua
+}
+
+type headerContextKey struct{}
+
+// WithHeader returns a context whose requests carry key: value in addition
+// to the client's headers, e.g. a per-operation request ID.
+func WithHeader(ctx context.Context, key, value string) context.Context {
+	h := headerFromContext(ctx).Clone()
+	if h == nil {
+		h = http.Header{}
+	}
+	h.Set(key, value)
+	return context.WithValue(ctx, headerContextKey{}, h)
+}
+
+func headerFromContext(ctx context.Context) http.Header {
+	h, _ := ctx.Value(headerContextKey{}).(http.Header)
+	return h
+}
+
+// HeaderFromMap converts a provider's Headers option to an http.Header,
+// or nil if there are none.
+func HeaderFromMap(m map[string]string) http.Header {
+	if len(m) == 0 {
+		return nil
+	}
+	h := make(http.Header, len(m))
+	for k, v := range m {
+		h.Set(k, v)
+	}
+	return h
+}
+
+// setHeaders adds h to req. Authorization and Content-Type are set by the
+// client and can't be overridden.
+func setHeaders(req *http.Request, h http.Header) {
+	for key, values := range h {
+		switch http.CanonicalHeaderKey(key) {
+		case "Authorization", "Content-Type":
+			continue
+		}
+		req.Header.Del(key)
+		for _, v := range values {
+			req.Header.Add(key, v)
+		}
+	}
+}
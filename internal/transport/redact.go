@@ -0,0 +1,70 @@
+package transport
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Redacted replaces secrets in String, GoString and log output.
+const Redacted = "[REDACTED]"
+
+// MaxErrorBody is the longest response excerpt kept in an HTTPError.
+const MaxErrorBody = 512
+
+// RedactSecret returns Redacted for a non-empty secret and "" otherwise, so
+// output still shows whether the secret is set.
+func RedactSecret(s string) string {
+	if s == "" {
+		return ""
+	}
+	return Redacted
+}
+
+var (
+	htmlTag     = regexp.MustCompile(`(?s)<(script|style)\b.*?</(script|style)>|<[^>]*>`)
+	bearerToken = regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9._~+/=-]+`)
+	whitespace  = regexp.MustCompile(`\s+`)
+)
+
+// Excerpt makes a response body safe to embed in an error: HTML markup is
+// stripped to its text, the token and anything that looks like a bearer
+// token are redacted, control characters and runs of whitespace become a
+// single space, and the result is cut to MaxErrorBody bytes.
+func Excerpt(body []byte, token string) string {
+	s := string(body)
+	if looksLikeHTML(s) {
+		s = htmlTag.ReplaceAllString(s, " ")
+	}
+	if token != "" {
+		s = strings.ReplaceAll(s, token, Redacted)
+	}
+	s = bearerToken.ReplaceAllString(s, "${1}"+Redacted)
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == unicode.ReplacementChar {
+			return ' '
+		}
+		return r
+	}, s)
+	s = strings.TrimSpace(whitespace.ReplaceAllString(s, " "))
+
+	if len(s) <= MaxErrorBody {
+		return s
+	}
+	cut := MaxErrorBody
+	for cut > 0 && !isRuneStart(s[cut]) {
+		cut--
+	}
+	return fmt.Sprintf("%s… (%d bytes total)", s[:cut], len(body))
+}
+
+func looksLikeHTML(s string) bool {
+	head := strings.ToLower(strings.TrimSpace(s))
+	if len(head) > 64 {
+		head = head[:64]
+	}
+	return strings.HasPrefix(head, "<!doctype html") || strings.HasPrefix(head, "<html") || strings.HasPrefix(head, "<?xml") || strings.HasPrefix(head, "<head") || strings.HasPrefix(head, "<body")
+}
+
+func isRuneStart(b byte) bool { return b&0xc0 != 0x80 }
@@ -0,0 +1,301 @@
+// Package transport holds the HTTP plumbing shared by the ACME and v2 API
+// clients: authentication, retries and error classification.
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type HTTPClient interface {
+	Do(*http.Request) (*http.Response, error)
+}
+
+var (
+	// ErrUnauthorized matches API errors caused by a missing, invalid or
+	// insufficiently privileged token.
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrZoneNotFound matches API errors for zones the API does not know.
+	ErrZoneNotFound = errors.New("zone not found")
+	// ErrResponseTooLarge is returned when a response body exceeds the
+	// configured maximum size.
+	ErrResponseTooLarge = errors.New("response too large")
+)
+
+// DefaultMaxResponseSize is the body size limit when none is configured.
+const DefaultMaxResponseSize = 4 << 20
+
+// DefaultErrorPrefix starts error messages of a Requester without a
+// Prefix.
+const DefaultErrorPrefix = "rcodezero http"
+
+// HTTPError is returned for non-2xx API responses. Body is a sanitized,
+// truncated excerpt of the response (see Excerpt).
+type HTTPError struct {
+	StatusCode  int
+	ContentType string
+	Body        string
+
+	// Prefix names the API in the message, e.g. "rcodezero acme http".
+	Prefix string
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("%s %d: %s", prefixOr(e.Prefix), e.StatusCode, e.Body)
+}
+
+func (e *HTTPError) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	case ErrZoneNotFound:
+		return e.StatusCode == http.StatusNotFound
+	}
+	return false
+}
+
+// NetworkError is returned when the request could not be completed at the
+// transport level (DNS, connect, TLS, timeouts).
+type NetworkError struct {
+	Err error
+}
+
+func (e *NetworkError) Error() string { return "http do: " + e.Err.Error() }
+func (e *NetworkError) Unwrap() error { return e.Err }
+
+// NonJSONError is returned for a 2xx response that is not the expected
+// JSON, such as an HTML maintenance page served with status 200. Excerpt is
+// a sanitized, truncated excerpt of the body; Err is the decoding error, if
+// decoding was attempted.
+type NonJSONError struct {
+	StatusCode  int
+	ContentType string
+	Excerpt     string
+	Err         error
+
+	// Prefix names the API in the message, as in HTTPError.
+	Prefix string
+}
+
+func (e *NonJSONError) Error() string {
+	ct := e.ContentType
+	if ct == "" {
+		ct = "no content type"
+	}
+	if e.Err != nil {
+		return fmt.Sprintf("%s %d: invalid JSON response (%s): %v: %s", prefixOr(e.Prefix), e.StatusCode, ct, e.Err, e.Excerpt)
+	}
+	return fmt.Sprintf("%s %d: non-JSON response (%s): %s", prefixOr(e.Prefix), e.StatusCode, ct, e.Excerpt)
+}
+
+func (e *NonJSONError) Unwrap() error { return e.Err }
+
+func prefixOr(prefix string) string {
+	if prefix == "" {
+		return DefaultErrorPrefix
+	}
+	return prefix
+}
+
+type zoneContextKey struct{}
+
+// WithZone records the zone a request is made for, so token sources can
+// select a zone-specific token.
+func WithZone(ctx context.Context, zone string) context.Context {
+	return context.WithValue(ctx, zoneContextKey{}, zone)
+}
+
+// ZoneFromContext returns the zone recorded by WithZone.
+func ZoneFromContext(ctx context.Context) (string, bool) {
+	zone, ok := ctx.Value(zoneContextKey{}).(string)
+	return zone, ok && zone != ""
+}
+
+// Requester sends authenticated JSON requests to the RcodeZero API.
+type Requester struct {
+	// Token returns the bearer token for a request.
+	Token func(ctx context.Context) (string, error)
+
+	// HTTP sends the requests; http.DefaultClient if nil.
+	HTTP HTTPClient
+
+	// Retries is how often a GET is retried after a network error, 429 or
+	// 502/503/504. Other methods are never retried.
+	Retries int
+
+	// MaxResponseSize limits response bodies; DefaultMaxResponseSize if
+	// zero or negative.
+	MaxResponseSize int64
+
+	// UserAgent replaces DefaultUserAgent; UserAgentSuffix is appended to
+	// either, e.g. "caddy/2.8".
+	UserAgent       string
+	UserAgentSuffix string
+
+	// Header is added to every request. It cannot override Authorization
+	// or Content-Type.
+	Header http.Header
+
+	// Prefix starts the messages of HTTP errors, naming the API, e.g.
+	// "rcodezero acme http"; DefaultErrorPrefix if empty.
+	Prefix string
+}
+
+const (
+	retryBaseDelay = 250 * time.Millisecond
+	retryMaxDelay  = 5 * time.Second
+)
+
+// Do sends req and decodes a 2xx JSON response into out (if non-nil).
+func (r *Requester) Do(req *http.Request, out any) error {
+	token, err := r.Token(req.Context())
+	if err != nil {
+		return fmt.Errorf("api token: %w", err)
+	}
+	setHeaders(req, r.Header)
+	setHeaders(req, headerFromContext(req.Context()))
+	req.Header.Set("User-Agent", r.userAgent())
+	req.Header.Set("Authorization", "Bearer "+token)
+	if req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", "application/json")
+	}
+
+	client := r.HTTP
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	retries := 0
+	if req.Method == http.MethodGet {
+		retries = r.Retries
+	}
+
+	limit := r.MaxResponseSize
+	if limit <= 0 {
+		limit = DefaultMaxResponseSize
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := send(client, req, token, limit, r.Prefix)
+		if err == nil {
+			return decode(resp, token, out, r.Prefix)
+		}
+		if attempt >= retries || !retryable(err) {
+			return err
+		}
+		if err := sleep(req.Context(), backoff(attempt, resp.retryAfter)); err != nil {
+			return err
+		}
+	}
+}
+
+// response is a read response of one attempt.
+type response struct {
+	status      int
+	contentType string
+	body        []byte
+	retryAfter  time.Duration
+}
+
+// send performs one attempt. It returns an error for transport failures,
+// oversized bodies and non-2xx responses.
+func send(client HTTPClient, req *http.Request, token string, limit int64, prefix string) (response, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return response{}, &NetworkError{Err: err}
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	out := response{status: resp.StatusCode, contentType: resp.Header.Get("Content-Type")}
+	ok := resp.StatusCode/100 == 2
+	if ok && resp.ContentLength > limit {
+		return out, fmt.Errorf("%s %d: %w: %d bytes exceeds limit of %d", prefixOr(prefix), resp.StatusCode, ErrResponseTooLarge, resp.ContentLength, limit)
+	}
+	out.body, err = io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return out, &NetworkError{Err: fmt.Errorf("read response: %w", err)}
+	}
+
+	if !ok {
+		// Spec doesn’t document a structured error payload; keep an excerpt.
+		// An oversized error body is cut, not rejected, so the status
+		// still classifies the error.
+		if s, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && s > 0 {
+			out.retryAfter = time.Duration(s) * time.Second
+		}
+		return out, &HTTPError{StatusCode: resp.StatusCode, ContentType: out.contentType, Body: Excerpt(out.body, token), Prefix: prefix}
+	}
+	if int64(len(out.body)) > limit {
+		return out, fmt.Errorf("%s %d: %w: more than %d bytes", prefixOr(prefix), resp.StatusCode, ErrResponseTooLarge, limit)
+	}
+	return out, nil
+}
+
+// decode unmarshals a 2xx response into out. Responses declaring a
+// non-JSON content type are rejected without decoding; responses without
+// one are decoded and rejected if that fails.
+func decode(resp response, token string, out any, prefix string) error {
+	if out == nil {
+		return nil
+	}
+	nonJSON := &NonJSONError{StatusCode: resp.status, ContentType: resp.contentType, Prefix: prefix}
+	if resp.contentType != "" && !isJSON(resp.contentType) {
+		nonJSON.Excerpt = Excerpt(resp.body, token)
+		return nonJSON
+	}
+	if err := json.Unmarshal(resp.body, out); err != nil {
+		nonJSON.Excerpt = Excerpt(resp.body, token)
+		nonJSON.Err = err
+		return nonJSON
+	}
+	return nil
+}
+
+func isJSON(contentType string) bool {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mt == "application/json" || strings.HasSuffix(mt, "+json")
+}
+
+func retryable(err error) bool {
+	var netErr *NetworkError
+	if errors.As(err, &netErr) {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		switch httpErr.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+	}
+	return false
+}
+
+func backoff(attempt int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		return min(retryAfter, retryMaxDelay)
+	}
+	return min(retryBaseDelay<<attempt, retryMaxDelay)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,156 @@
+// Package zonefmt converts record data between zone-file presentation
+// format and plain values.
+package zonefmt
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxTXTSegment is the longest character-string a TXT record can carry
+// (RFC 1035 section 3.3).
+const MaxTXTSegment = 255
+
+// ParseTXT decodes TXT rdata in zone-file presentation format into the
+// record's text: the character-strings of ParseTXTSegments, concatenated.
+func ParseTXT(content string) (string, error) {
+	segs, err := ParseTXTSegments(content)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(segs, ""), nil
+}
+
+// ParseTXTSegments splits TXT rdata in presentation format into its
+// character-strings. The rdata is a sequence of strings, quoted or not,
+// separated by whitespace; \X stands for X and \DDD for the byte with
+// decimal value DDD.
+func ParseTXTSegments(content string) ([]string, error) {
+	var segs []string
+	s := strings.TrimSpace(content)
+
+	for i := 0; i < len(s); {
+		if s[i] == ' ' || s[i] == '\t' {
+			i++
+			continue
+		}
+
+		var out []byte
+		quoted := s[i] == '"'
+		if quoted {
+			i++
+		}
+		closed := !quoted
+		for i < len(s) {
+			c := s[i]
+			if quoted && c == '"' {
+				i++
+				closed = true
+				break
+			}
+			if !quoted && (c == ' ' || c == '\t') {
+				break
+			}
+			if !quoted && c == '"' {
+				return nil, fmt.Errorf("txt: unexpected quote at offset %d", i)
+			}
+			if c != '\\' {
+				out = append(out, c)
+				i++
+				continue
+			}
+
+			if i+1 >= len(s) {
+				return nil, fmt.Errorf("txt: dangling backslash")
+			}
+			if isDigit(s[i+1]) {
+				if i+4 > len(s) || !isDigit(s[i+2]) || !isDigit(s[i+3]) {
+					return nil, fmt.Errorf("txt: short \\DDD escape at offset %d", i)
+				}
+				v := int(s[i+1]-'0')*100 + int(s[i+2]-'0')*10 + int(s[i+3]-'0')
+				if v > 255 {
+					return nil, fmt.Errorf("txt: \\DDD escape out of range at offset %d", i)
+				}
+				out = append(out, byte(v))
+				i += 4
+				continue
+			}
+			out = append(out, s[i+1])
+			i += 2
+		}
+		if !closed {
+			return nil, fmt.Errorf("txt: unterminated quoted string")
+		}
+		segs = append(segs, string(out))
+	}
+
+	return segs, nil
+}
+
+// FormatTXT encodes text as TXT rdata in presentation format: quoted
+// character-strings of at most 255 bytes, with '"' and '\' escaped and
+// bytes outside printable ASCII written as \DDD. Valid UTF-8 is not split
+// inside a character.
+func FormatTXT(text string) string {
+	if text == "" {
+		return `""`
+	}
+
+	var b strings.Builder
+	valid := utf8.ValidString(text)
+	for start := 0; start < len(text); {
+		end := start + MaxTXTSegment
+		if end >= len(text) {
+			end = len(text)
+		} else if valid {
+			for end > start+1 && !utf8.RuneStart(text[end]) {
+				end--
+			}
+		}
+		if start > 0 {
+			b.WriteByte(' ')
+		}
+		writeQuoted(&b, text[start:end])
+		start = end
+	}
+	return b.String()
+}
+
+// Quote encodes s as a single quoted string with the escaping of
+// FormatTXT, for rdata fields such as the CAA value that are not split
+// into segments.
+func Quote(s string) string {
+	var b strings.Builder
+	writeQuoted(&b, s)
+	return b.String()
+}
+
+func writeQuoted(b *strings.Builder, s string) {
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < 0x20 || c > 0x7e:
+			fmt.Fprintf(b, "\\%03d", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+}
+
+// DecodeTXT is ParseTXT for API responses: content that is not valid
+// presentation format is returned trimmed rather than dropped.
+func DecodeTXT(content string) string {
+	text, err := ParseTXT(content)
+	if err != nil {
+		return strings.TrimSpace(content)
+	}
+	return text
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
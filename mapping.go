@@ -18,19 +18,18 @@ func isAcmeChallengeName(name string) bool {
 	return strings.HasPrefix(n, "_acme-challenge.")
 }
 
-func unquoteTXT(s string) string {
-    s = strings.TrimSpace(s)
-    if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
-        return s[1 : len(s)-1]
-    }
-    return s
-}
-
+// normalizeTXT cleans up a caller-supplied TXT value. Callers sometimes
+// pass the value wrapped in one pair of quotes; those are removed. The value
+// is otherwise taken literally (it is not presentation format).
 func normalizeTXT(s string) string {
-    return unquoteTXT(strings.TrimSpace(s))
+	s = strings.TrimSpace(s)
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		return s[1 : len(s)-1]
+	}
+	return s
 }
 
-func ensureAcmeTXT(zone string, r libdns.Record) (fqdn string, txt string, ttlSec int, err error) {
+func ensureAcmeTXT(zone string, r libdns.Record, policy NamePolicy) (fqdn string, txt string, ttlSec int, err error) {
 	zoneFQDN := strings.TrimSuffix(strings.TrimSpace(zone), ".") + "."
 	if zoneFQDN == "." {
 		return "", "", 0, fmt.Errorf("empty zone")
@@ -61,27 +60,47 @@ func ensureAcmeTXT(zone string, r libdns.Record) (fqdn string, txt string, ttlSe
 		return "", "", 0, fmt.Errorf("acme-only provider supports only TXT records (got %T)", r)
 	}
 
-	abs := libdns.AbsoluteName(relName, zoneFQDN)
+	abs, err := toASCIIName(stripWildcardLabel(libdns.AbsoluteName(relName, zoneFQDN)))
+	if err != nil {
+		return "", "", 0, err
+	}
 
-	if !isAcmeChallengeName(abs) {
-		return "", "", 0, fmt.Errorf("only _acme-challenge TXT records are allowed (got %q)", abs)
+	if err := policy.check(abs); err != nil {
+		return "", "", 0, err
 	}
 
 	// Keep trailing dot in name (API examples use it)
 	fqdn = abs
 
+	// Zero when unset; the provider's TTLPolicy supplies the default.
 	ttlSec = durationToSeconds(ttl)
-	if ttlSec == 0 {
-		ttlSec = 60
-	}
 
-	// Keep value as-is (your provider already normalizes on GetRecords via unquoteTXT()).
-	// If you want to force quoting for the API, do it here consistently.
+	// Keep value as-is; callers normalize it and formatTXT() encodes it
+	// for the API.
 	txt = value
 
 	return fqdn, txt, ttlSec, nil
 }
 
+// stripWildcardLabel drops a "*" label that directly follows a leading
+// underscore label, as produced by prefixing "_acme-challenge." to a
+// wildcard domain: "_acme-challenge.*.example.com." ->
+// "_acme-challenge.example.com.". The challenge for *.example.com lives at
+// the same name as the one for example.com.
+func stripWildcardLabel(name string) string {
+	labels := strings.Split(name, ".")
+	for i := 1; i < len(labels); i++ {
+		if labels[i] != "*" {
+			continue
+		}
+		if !strings.HasPrefix(labels[i-1], "_") {
+			break
+		}
+		return strings.Join(append(labels[:i:i], labels[i+1:]...), ".")
+	}
+	return name
+}
+
 func durationToSeconds(d time.Duration) int {
 	if d <= 0 {
 		return 0
@@ -15,7 +15,7 @@ func TestEnsureAcmeTXT_HardFailAndPass(t *testing.T) {
 		Name:   "_acme-challenge",
 		Target: "x.example.com.",
 		TTL:    time.Minute,
-	})
+	}, NamePolicy{})
 	if err == nil {
 		t.Fatalf("expected error for non-TXT record")
 	}
@@ -25,7 +25,7 @@ func TestEnsureAcmeTXT_HardFailAndPass(t *testing.T) {
 		Name: "www",
 		Text: "nope",
 		TTL:  time.Minute,
-	})
+	}, NamePolicy{})
 	if err == nil {
 		t.Fatalf("expected error for non-_acme-challenge TXT")
 	}
@@ -35,9 +35,8 @@ func TestEnsureAcmeTXT_HardFailAndPass(t *testing.T) {
 		Name: "_acme-challenge",
 		Text: "ok",
 		TTL:  time.Minute,
-	})
+	}, NamePolicy{})
 	if err != nil {
 		t.Fatalf("expected success, got %v", err)
 	}
 }
-
@@ -0,0 +1,105 @@
+package rcodezeroacme
+
+import (
+	"fmt"
+	"strings"
+)
+
+const persistLabel = "_validation-persist"
+
+// NamePolicy decides which TXT record names the provider manages. The zero
+// value only allows _acme-challenge names (DNS-01).
+type NamePolicy struct {
+	// AllowAccountLabels also allows DNS-ACCOUNT-01 names of the form
+	// _<label>._acme-challenge[.<name>], where <label> is the 10-character
+	// base32 account label.
+	AllowAccountLabels bool
+
+	// AllowPersist also allows DNS-PERSIST-01 names of the form
+	// _validation-persist[.<name>].
+	AllowPersist bool
+
+	// ExtraLabels lists further leading labels to allow, e.g.
+	// "_custom-validation" allows "_custom-validation[.<name>]". Only use
+	// labels the RcodeZero ACME endpoint accepts.
+	ExtraLabels []string
+}
+
+// Allows reports whether name (relative or absolute) may be managed.
+func (np NamePolicy) Allows(name string) bool {
+	if np.allowsChallenge(name) {
+		return true
+	}
+	if np.AllowPersist && hasLeadingLabel(name, persistLabel) {
+		return true
+	}
+	for _, l := range np.ExtraLabels {
+		if hasLeadingLabel(name, l) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowsChallenge reports whether name is a short-lived challenge name
+// (DNS-01 or, if enabled, DNS-ACCOUNT-01), as opposed to a persistent one.
+func (np NamePolicy) allowsChallenge(name string) bool {
+	return isAcmeChallengeName(name) || (np.AllowAccountLabels && isAccountChallengeName(name))
+}
+
+// patterns describes the allowed names for error messages.
+func (np NamePolicy) patterns() []string {
+	out := []string{"_acme-challenge[.<name>]"}
+	if np.AllowAccountLabels {
+		out = append(out, "_<account-label>._acme-challenge[.<name>]")
+	}
+	if np.AllowPersist {
+		out = append(out, persistLabel+"[.<name>]")
+	}
+	for _, l := range np.ExtraLabels {
+		out = append(out, strings.ToLower(strings.TrimSpace(l))+"[.<name>]")
+	}
+	return out
+}
+
+func (np NamePolicy) check(name string) error {
+	if np.Allows(name) {
+		return nil
+	}
+	return fmt.Errorf("record name %q is not allowed; allowed names: %s", name, strings.Join(np.patterns(), ", "))
+}
+
+// isAccountChallengeName reports whether name has the DNS-ACCOUNT-01 form
+// _<label>._acme-challenge[.<name>].
+func isAccountChallengeName(name string) bool {
+	n := normalizeName(name)
+	first, rest, ok := strings.Cut(n, ".")
+	if !ok || !isAccountLabel(first) {
+		return false
+	}
+	return isAcmeChallengeName(rest)
+}
+
+// isAccountLabel reports whether l is "_" followed by ten base32
+// characters (lower-case letters and digits 2-7).
+func isAccountLabel(l string) bool {
+	if len(l) != 11 || l[0] != '_' {
+		return false
+	}
+	for i := 1; i < len(l); i++ {
+		c := l[i]
+		if !(c >= 'a' && c <= 'z') && !(c >= '2' && c <= '7') {
+			return false
+		}
+	}
+	return true
+}
+
+func hasLeadingLabel(name, label string) bool {
+	n := normalizeName(name)
+	l := strings.ToLower(strings.TrimSpace(label))
+	if l == "" {
+		return false
+	}
+	return n == l || strings.HasPrefix(n, l+".")
+}
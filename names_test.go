@@ -0,0 +1,50 @@
+package rcodezeroacme
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNamePolicy_Allows(t *testing.T) {
+	strict := NamePolicy{}
+	all := NamePolicy{AllowAccountLabels: true, AllowPersist: true, ExtraLabels: []string{"_custom"}}
+
+	cases := []struct {
+		name          string
+		strict, relax bool
+	}{
+		{"_acme-challenge.example.com.", true, true},
+		{"_ACME-Challenge.sub", true, true},
+		{"_ujmmovf2vn._acme-challenge.example.com.", false, true},
+		{"_UJMMOVF2VN._acme-challenge", false, true},
+		{"_short._acme-challenge.example.com.", false, false},
+		{"_validation-persist.example.com.", false, true},
+		{"_custom.example.com.", false, true},
+		{"_customer.example.com.", false, false},
+		{"www.example.com.", false, false},
+	}
+	for _, c := range cases {
+		if got := strict.Allows(c.name); got != c.strict {
+			t.Errorf("strict.Allows(%q) = %v", c.name, got)
+		}
+		if got := all.Allows(c.name); got != c.relax {
+			t.Errorf("relaxed.Allows(%q) = %v", c.name, got)
+		}
+	}
+
+	if all.allowsChallenge("_validation-persist.example.com.") {
+		t.Errorf("persist names must not count as challenge names")
+	}
+}
+
+func TestNamePolicy_ErrorListsPatterns(t *testing.T) {
+	err := NamePolicy{AllowPersist: true}.check("www.example.com.")
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	for _, want := range []string{"www.example.com.", "_acme-challenge[.<name>]", "_validation-persist[.<name>]"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+}
@@ -0,0 +1,285 @@
+package rcodezeroacme
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+// PersistRecord is the content of a DNS-PERSIST-01 validation record:
+//
+//	<issuer-domain>; accounturi=<uri>[; policy=<policy>][; persistUntil=<unix-time>]
+type PersistRecord struct {
+	// IssuerDomain is the CA's issuer domain name, e.g. "letsencrypt.org".
+	IssuerDomain string
+	// AccountURI is the ACME account allowed to use this record.
+	AccountURI string
+	// Policy is optional; "wildcard" also authorizes wildcard certificates.
+	Policy string
+	// PersistUntil optionally limits how long the record may be relied on.
+	PersistUntil time.Time
+	// Params holds any further key=value parameters.
+	Params map[string]string
+}
+
+const (
+	persistParamAccountURI   = "accounturi"
+	persistParamPolicy       = "policy"
+	persistParamPersistUntil = "persistuntil"
+)
+
+// String formats the record as TXT text.
+func (r PersistRecord) String() string {
+	parts := []string{strings.TrimSpace(r.IssuerDomain)}
+	if r.AccountURI != "" {
+		parts = append(parts, "accounturi="+r.AccountURI)
+	}
+	if r.Policy != "" {
+		parts = append(parts, "policy="+r.Policy)
+	}
+	if !r.PersistUntil.IsZero() {
+		parts = append(parts, "persistUntil="+strconv.FormatInt(r.PersistUntil.Unix(), 10))
+	}
+
+	keys := make([]string, 0, len(r.Params))
+	for k := range r.Params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		parts = append(parts, k+"="+r.Params[k])
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Validate checks that the record has the fields the method requires.
+func (r PersistRecord) Validate() error {
+	if strings.TrimSpace(r.IssuerDomain) == "" {
+		return fmt.Errorf("persist record: issuer domain is required")
+	}
+	if strings.ContainsAny(r.IssuerDomain, "; =") {
+		return fmt.Errorf("persist record: invalid issuer domain %q", r.IssuerDomain)
+	}
+	if r.AccountURI == "" {
+		return fmt.Errorf("persist record: accounturi is required")
+	}
+	// ';' separates parameters, so no value may contain it. Values may
+	// contain '='; only the first one in a parameter ends its name.
+	if strings.Contains(r.AccountURI, ";") {
+		return fmt.Errorf("persist record: invalid accounturi %q", r.AccountURI)
+	}
+	if strings.Contains(r.Policy, ";") {
+		return fmt.Errorf("persist record: invalid policy %q", r.Policy)
+	}
+	for k, v := range r.Params {
+		if strings.TrimSpace(k) == "" || strings.ContainsAny(k, "; =") || strings.Contains(v, ";") {
+			return fmt.Errorf("persist record: invalid parameter %q=%q", k, v)
+		}
+	}
+	return nil
+}
+
+// ParsePersistRecord parses DNS-PERSIST-01 TXT text. Parameter names are
+// matched case-insensitively.
+func ParsePersistRecord(s string) (PersistRecord, error) {
+	fields := strings.Split(s, ";")
+	r := PersistRecord{IssuerDomain: strings.TrimSpace(fields[0])}
+
+	for _, f := range fields[1:] {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(f, "=")
+		if !ok {
+			return PersistRecord{}, fmt.Errorf("persist record: parameter %q has no value", f)
+		}
+		k, v = strings.TrimSpace(k), strings.TrimSpace(v)
+
+		switch strings.ToLower(k) {
+		case persistParamAccountURI:
+			r.AccountURI = v
+		case persistParamPolicy:
+			r.Policy = v
+		case persistParamPersistUntil:
+			sec, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return PersistRecord{}, fmt.Errorf("persist record: invalid persistUntil %q", v)
+			}
+			r.PersistUntil = time.Unix(sec, 0).UTC()
+		default:
+			if r.Params == nil {
+				r.Params = map[string]string{}
+			}
+			r.Params[k] = v
+		}
+	}
+
+	if err := r.Validate(); err != nil {
+		return PersistRecord{}, err
+	}
+	return r, nil
+}
+
+// PersistEntry is a DNS-PERSIST-01 record found in a zone.
+type PersistEntry struct {
+	// Name is the record name relative to the zone.
+	Name   string
+	TTL    time.Duration
+	Record PersistRecord
+	// Text is the TXT value as stored.
+	Text string
+}
+
+// persistName returns "_validation-persist.<domain>." relative to zone,
+// with internationalized labels in their ASCII form.
+func persistName(zone, domain string) (string, error) {
+	zoneTrim, err := trimZone(zone)
+	if err != nil {
+		return "", err
+	}
+	d, err := toASCIIName(strings.TrimPrefix(strings.TrimSuffix(strings.TrimSpace(domain), "."), "*."))
+	if err != nil {
+		return "", err
+	}
+	return relativeToZone(persistLabel+"."+d+".", zoneTrim), nil
+}
+
+// persistTTLPolicy is the TTL policy for DNS-PERSIST-01 records. They are
+// meant to stay, so the challenge TTLPolicy does not apply to them.
+func (p *Provider) persistTTLPolicy() TTLPolicy {
+	if p.PersistTTL > 0 {
+		return TTLPolicy{Default: p.PersistTTL}
+	}
+	return TTLPolicy{Default: defaultPersistTTL}
+}
+
+// persistPolicy is the provider's name policy with DNS-PERSIST-01 names
+// allowed, for the persist methods below.
+func (p *Provider) persistPolicy() NamePolicy {
+	np := p.NamePolicy
+	np.AllowPersist = true
+	return np
+}
+
+// InstallPersist adds a DNS-PERSIST-01 record for domain to zone. Existing
+// values at the name (e.g. for other issuers) are kept.
+func (p *Provider) InstallPersist(ctx context.Context, zone, domain string, rec PersistRecord) (libdns.TXT, error) {
+	if err := rec.Validate(); err != nil {
+		return libdns.TXT{}, err
+	}
+	name, err := persistName(zone, domain)
+	if err != nil {
+		return libdns.TXT{}, err
+	}
+	txt := libdns.TXT{Name: name, Text: rec.String()}
+	if _, err := p.appendRecords(ctx, zone, []libdns.Record{txt}, p.persistPolicy()); err != nil {
+		return libdns.TXT{}, err
+	}
+	return txt, nil
+}
+
+// ListPersist returns the DNS-PERSIST-01 records in zone. Values that do not
+// parse as persist records are skipped.
+func (p *Provider) ListPersist(ctx context.Context, zone string) ([]PersistEntry, error) {
+	if err := p.init(); err != nil {
+		return nil, err
+	}
+	zoneTrim, err := trimZone(zone)
+	if err != nil {
+		return nil, err
+	}
+	if zoneTrim == "" {
+		return nil, fmt.Errorf("empty zone")
+	}
+
+	var out []PersistEntry
+	page, pageSize := 1, 100
+	for {
+		resp, err := p.client.GetRRsets(ctx, zoneTrim, page, pageSize)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, rrset := range resp.Data {
+			if strings.ToUpper(rrset.Type) != "TXT" || !hasLeadingLabel(rrset.Name, persistLabel) {
+				continue
+			}
+			for _, r := range rrset.Records {
+				if r.Disabled {
+					continue
+				}
+				text := decodeTXT(r.Content)
+				rec, err := ParsePersistRecord(text)
+				if err != nil {
+					continue
+				}
+				out = append(out, PersistEntry{
+					Name:   libdns.RelativeName(rrset.Name, zoneTrim+"."),
+					TTL:    timeSeconds(rrset.TTL),
+					Record: rec,
+					Text:   text,
+				})
+			}
+		}
+
+		if resp.LastPage <= page || resp.LastPage == 0 {
+			break
+		}
+		page++
+	}
+
+	return out, nil
+}
+
+// RevokePersist removes the DNS-PERSIST-01 records for domain issued to
+// issuerDomain (all issuers if empty) and returns what it removed.
+func (p *Provider) RevokePersist(ctx context.Context, zone, domain, issuerDomain string) ([]PersistEntry, error) {
+	entries, err := p.ListPersist(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	name, err := persistName(zone, domain)
+	if err != nil {
+		return nil, err
+	}
+	want := normalizeName(name)
+	var (
+		matched []PersistEntry
+		dels    []libdns.Record
+	)
+	for _, e := range entries {
+		if normalizeName(e.Name) != want {
+			continue
+		}
+		if issuerDomain != "" && !strings.EqualFold(e.Record.IssuerDomain, issuerDomain) {
+			continue
+		}
+		matched = append(matched, e)
+		dels = append(dels, libdns.TXT{Name: e.Name, Text: e.Text})
+	}
+	if len(dels) == 0 {
+		return nil, nil
+	}
+
+	// Report only what was actually removed: OwnedOnly may skip values, and
+	// a failed PATCH leaves the rest in place.
+	deleted, err := p.deleteRecords(ctx, zone, dels, p.persistPolicy())
+	var revoked []PersistEntry
+	for _, d := range deleted {
+		rr := d.RR()
+		for _, e := range matched {
+			if asciiKey(e.Name) == asciiKey(rr.Name) && e.Text == rr.Data {
+				revoked = append(revoked, e)
+				break
+			}
+		}
+	}
+	return revoked, err
+}
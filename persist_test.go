@@ -0,0 +1,143 @@
+package rcodezeroacme
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kagescode/libdns-rcodezeroacme/internal/apitest"
+)
+
+func TestPersistRecord_RoundTrip(t *testing.T) {
+	rec := PersistRecord{
+		IssuerDomain: "letsencrypt.org",
+		AccountURI:   "https://acme-v02.api.letsencrypt.org/acme/acct/1234",
+		Policy:       "wildcard",
+		PersistUntil: time.Unix(1767225600, 0).UTC(),
+		Params:       map[string]string{"x-note": "ops"},
+	}
+	s := rec.String()
+	want := "letsencrypt.org; accounturi=https://acme-v02.api.letsencrypt.org/acme/acct/1234; policy=wildcard; persistUntil=1767225600; x-note=ops"
+	if s != want {
+		t.Fatalf("String() = %q", s)
+	}
+
+	got, err := ParsePersistRecord(" letsencrypt.org ;AccountURI=https://acme-v02.api.letsencrypt.org/acme/acct/1234; policy=wildcard;persistUntil=1767225600; x-note=ops")
+	if err != nil {
+		t.Fatalf("ParsePersistRecord: %v", err)
+	}
+	if got.String() != want {
+		t.Errorf("parsed record formats as %q", got.String())
+	}
+
+	for _, bad := range []string{"", "letsencrypt.org", "ca.example; accounturi", "ca.example; accounturi=u; persistUntil=soon"} {
+		if _, err := ParsePersistRecord(bad); err == nil {
+			t.Errorf("ParsePersistRecord(%q): expected error", bad)
+		}
+	}
+}
+
+func TestPersistRecord_Validate(t *testing.T) {
+	base := PersistRecord{IssuerDomain: "ca.example", AccountURI: "https://ca.example/acct/1"}
+
+	withEq := base
+	withEq.AccountURI = "https://ca.example/acct?id=1"
+	withEq.Params = map[string]string{"x-sig": "YWJj=="}
+	if err := withEq.Validate(); err != nil {
+		t.Fatalf("values with '=': %v", err)
+	}
+	got, err := ParsePersistRecord(withEq.String())
+	if err != nil || got.AccountURI != withEq.AccountURI || got.Params["x-sig"] != "YWJj==" {
+		t.Fatalf("round trip of values with '=' = %+v, %v", got, err)
+	}
+
+	bad := map[string]func(*PersistRecord){
+		"accounturi with ;":  func(r *PersistRecord) { r.AccountURI = "https://ca.example/a;b" },
+		"policy with ;":      func(r *PersistRecord) { r.Policy = "wildcard; x=y" },
+		"param value with ;": func(r *PersistRecord) { r.Params = map[string]string{"x": "a;b"} },
+		"param name with =":  func(r *PersistRecord) { r.Params = map[string]string{"a=b": "c"} },
+		"empty param name":   func(r *PersistRecord) { r.Params = map[string]string{"": "c"} },
+	}
+	for name, mutate := range bad {
+		r := base
+		mutate(&r)
+		if err := r.Validate(); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestPersist_InstallListRevoke(t *testing.T) {
+	api := newFakeACME()
+	p := &Provider{APIToken: apitest.Token, HTTPClient: api}
+	ctx := context.Background()
+
+	le := PersistRecord{IssuerDomain: "letsencrypt.org", AccountURI: "https://le.example/acct/1"}
+	other := PersistRecord{IssuerDomain: "ca.example", AccountURI: "https://ca.example/acct/9", Policy: "wildcard"}
+
+	for _, rec := range []PersistRecord{le, other} {
+		txt, err := p.InstallPersist(ctx, "example.com.", "*.www.example.com", rec)
+		if err != nil {
+			t.Fatalf("InstallPersist: %v", err)
+		}
+		if txt.Name != "_validation-persist.www" {
+			t.Fatalf("name = %q", txt.Name)
+		}
+	}
+
+	entries, err := p.ListPersist(ctx, "example.com.")
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("ListPersist = %+v, %v", entries, err)
+	}
+
+	revoked, err := p.RevokePersist(ctx, "example.com.", "www.example.com", "LetsEncrypt.org")
+	if err != nil || len(revoked) != 1 || revoked[0].Record.IssuerDomain != "letsencrypt.org" {
+		t.Fatalf("RevokePersist = %+v, %v", revoked, err)
+	}
+	if got := api.values("_validation-persist.www.example.com."); len(got) != 1 || got[0] != other.String() {
+		t.Errorf("remaining values = %q", got)
+	}
+}
+
+func TestPersist_IDNAndTTL(t *testing.T) {
+	api := newFakeACME()
+	p := &Provider{APIToken: apitest.Token, HTTPClient: api, TTLPolicy: TTLPolicy{Max: 2 * time.Minute}}
+	ctx := context.Background()
+
+	rec := PersistRecord{IssuerDomain: "letsencrypt.org", AccountURI: "https://le.example/acct/1"}
+	txt, err := p.InstallPersist(ctx, "bücher.example.", "shop.bücher.example", rec)
+	if err != nil {
+		t.Fatalf("InstallPersist: %v", err)
+	}
+	if txt.Name != "_validation-persist.shop" {
+		t.Fatalf("name = %q", txt.Name)
+	}
+	rr := api.rrsets["_validation-persist.shop.xn--bcher-kva.example."]
+	if rr == nil || rr.TTL != 3600 {
+		t.Fatalf("persist rrset = %+v, want TTL 3600 despite the challenge Max", rr)
+	}
+}
+
+func TestPersist_RevokeReportsOnlyDeleted(t *testing.T) {
+	api := newFakeACME()
+	ctx := context.Background()
+
+	theirs := PersistRecord{IssuerDomain: "letsencrypt.org", AccountURI: "https://le.example/acct/1"}
+	mine := PersistRecord{IssuerDomain: "letsencrypt.org", AccountURI: "https://le.example/acct/2"}
+	other := &Provider{APIToken: apitest.Token, HTTPClient: api}
+	if _, err := other.InstallPersist(ctx, "example.com.", "example.com", theirs); err != nil {
+		t.Fatalf("InstallPersist: %v", err)
+	}
+	p := &Provider{APIToken: apitest.Token, HTTPClient: api, OwnedOnly: true}
+	if _, err := p.InstallPersist(ctx, "example.com.", "example.com", mine); err != nil {
+		t.Fatalf("InstallPersist: %v", err)
+	}
+
+	revoked, err := p.RevokePersist(ctx, "example.com.", "example.com", "")
+	if err != nil || len(revoked) != 1 || revoked[0].Record.AccountURI != mine.AccountURI {
+		t.Fatalf("RevokePersist = %+v, %v; want only the owned record", revoked, err)
+	}
+	if got := api.values("_validation-persist.example.com."); len(got) != 1 || got[0] != theirs.String() {
+		t.Errorf("remaining values = %q", got)
+	}
+}
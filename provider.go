@@ -7,34 +7,170 @@ import (
 	"time"
 
 	"github.com/libdns/libdns"
+
+	"github.com/kagescode/libdns-rcodezeroacme/internal/transport"
 )
 
 type Provider struct {
 	APIToken string
 	BaseURL  string
 
+	// TokenSource, if set, is consulted for the bearer token on every
+	// request and takes precedence over APIToken. Use it to pick up
+	// rotated credentials without restarting.
+	TokenSource TokenSource
+
+	// NamePolicy selects which TXT names may be managed. The zero value
+	// allows only _acme-challenge names.
+	NamePolicy NamePolicy
+
+	// ValueValidation configures checks on TXT values before they are
+	// sent. By default only empty values are rejected.
+	ValueValidation ValueValidation
+
+	// TTLPolicy controls the TTL of written rrsets. The zero value uses
+	// the record TTL, 60s if unset, and keeps the TTL of existing rrsets.
+	TTLPolicy TTLPolicy
+
+	// UnicodeNames makes GetRecords return internationalized names in
+	// Unicode form ("_acme-challenge.bücher") instead of punycode. Zones and
+	// names passed in are always accepted in either form.
+	UnicodeNames bool
+
+	// Zones optionally lists the zones this provider manages. ListZones
+	// probes them when the token may not list zones, and Verify checks them
+	// when called without arguments.
+	Zones []string
+
+	// ZoneTokens maps zones to API tokens for accounts that issue
+	// per-zone credentials. A key matches that zone and every zone below
+	// it; the longest match wins. Zones without a match fall back to
+	// TokenSource or APIToken.
+	ZoneTokens map[string]string
+
 	HTTPClient HTTPClient
 
-	client *Client
+	// Retries is how often a failed read is retried (network errors, 429,
+	// 502/503/504). Writes are never retried.
+	Retries int
+
+	// MaxResponseSize limits API response bodies in bytes; 4 MiB if zero.
+	MaxResponseSize int64
+
+	// UserAgentSuffix is appended to the User-Agent to identify the
+	// caller, e.g. "caddy/2.8".
+	UserAgentSuffix string
+
+	// Headers are sent with every API request, e.g. for tracing.
+	Headers map[string]string
+
+	// PersistTTL is the TTL written for DNS-PERSIST-01 records. Zero means
+	// one hour; TTLPolicy only applies to challenge records.
+	PersistTTL time.Duration
+
+	// Store records which challenge values this provider created. It
+	// defaults to an in-memory store; use a persistent one to keep ownership
+	// across restarts. The file stores are for a single process; sharing
+	// ownership between instances needs a Store backed by shared storage.
+	Store Store
+
+	// CreatorID is recorded with every value written to Store.
+	CreatorID string
+
+	// OwnedOnly makes DeleteRecords skip values that are not in Store, so
+	// challenge values written by other systems are never removed. Skipped
+	// records are not included in the returned slice.
+	//
+	// The default is false: DeleteRecords then removes every matching value,
+	// whoever created it, and Store is only bookkeeping.
+	OwnedOnly bool
+
+	// TrackSerial makes every change look up the zone serial that contains
+	// it, available from LastSerial for use with WaitForSerial.
+	TrackSerial bool
+
+	// Nameservers overrides the authoritative servers ("host" or
+	// "host:port") queried for SOA serials. By default the zone's NS
+	// records are used.
+	Nameservers []string
+
+	client  *Client
+	mem     MemoryStore
+	serials serialTracker
 }
 
 func (p *Provider) init() error {
 	if p.client != nil {
 		return nil
 	}
-	c, err := NewClient(p.APIToken, p.BaseURL, p.HTTPClient)
+	c, err := NewClientWithTokenSource(p.tokenSource(), p.BaseURL, p.HTTPClient)
 	if err != nil {
 		return err
 	}
+	c.Retries = p.Retries
+	c.MaxResponseSize = p.MaxResponseSize
+	c.UserAgentSuffix = p.UserAgentSuffix
+	c.Header = transport.HeaderFromMap(p.Headers)
 	p.client = c
 	return nil
 }
 
+// tokenSource assembles the TokenSource described by the Provider fields.
+func (p *Provider) tokenSource() TokenSource {
+	var def TokenSource
+	switch {
+	case p.TokenSource != nil:
+		def = p.TokenSource
+	case strings.TrimSpace(p.APIToken) != "":
+		def = StaticToken(p.APIToken)
+	}
+
+	if len(p.ZoneTokens) == 0 {
+		if def == nil {
+			// Surfaces "APIToken is required" on first use.
+			return StaticToken("")
+		}
+		return def
+	}
+
+	zones := make(map[string]TokenSource, len(p.ZoneTokens))
+	for zone, tok := range p.ZoneTokens {
+		zones[zone] = StaticToken(tok)
+	}
+	return ZoneTokens{Zones: zones, Default: def}
+}
+
+// ownership returns the configured Store or the built-in memory store.
+func (p *Provider) ownership() Store {
+	if p.Store != nil {
+		return p.Store
+	}
+	return &p.mem
+}
+
+// trackOwned records a value this provider just created.
+func (p *Provider) trackOwned(ctx context.Context, zoneTrim, fqdn, txt string) error {
+	err := p.ownership().Put(ctx, OwnedValue{
+		Zone:    zoneTrim,
+		Name:    fqdn,
+		Value:   txt,
+		Creator: p.CreatorID,
+		Created: time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("record %s created but not tracked: %w", fqdn, err)
+	}
+	return nil
+}
+
 func (p *Provider) GetRecords(ctx context.Context, zone string) ([]libdns.Record, error) {
 	if err := p.init(); err != nil {
 		return nil, err
 	}
-	zoneTrim := strings.TrimSuffix(strings.TrimSpace(zone), ".")
+	zoneTrim, err := trimZone(zone)
+	if err != nil {
+		return nil, err
+	}
 	if zoneTrim == "" {
 		return nil, fmt.Errorf("empty zone")
 	}
@@ -53,21 +189,16 @@ func (p *Provider) GetRecords(ctx context.Context, zone string) ([]libdns.Record
 			if strings.ToUpper(rrset.Type) != "TXT" {
 				continue
 			}
-                        if !isAcmeChallengeName(rrset.Name) {
-                            continue
-                        }
+			if !p.NamePolicy.Allows(rrset.Name) {
+				continue
+			}
 
 			for _, rec := range rrset.Records {
 				if rec.Disabled {
 					continue
 				}
 				// Map back to libdns TXT
-				nameRel := libdns.RelativeName(rrset.Name, zoneTrim+".")
-				out = append(out, libdns.TXT{
-					Name: nameRel,
-					Text: normalizeTXT(rec.Content),
-					TTL:  timeSeconds(rrset.TTL),
-				})
+				out = append(out, p.txtRecord(zoneTrim, rrset.Name, decodeTXT(rec.Content), rrset.TTL))
 			}
 		}
 
@@ -80,112 +211,219 @@ func (p *Provider) GetRecords(ctx context.Context, zone string) ([]libdns.Record
 	return out, nil
 }
 
+// AppendRecords adds the TXT values to their rrsets, keeping values already
+// present. The returned records describe the values as stored: zone-relative
+// name, normalized text and the TTL the rrset ended up with.
 func (p *Provider) AppendRecords(ctx context.Context, zone string, recs []libdns.Record) ([]libdns.Record, error) {
+	return p.appendRecords(ctx, zone, recs, p.NamePolicy)
+}
+
+func (p *Provider) appendRecords(ctx context.Context, zone string, recs []libdns.Record, policy NamePolicy) ([]libdns.Record, error) {
 	if err := p.init(); err != nil {
 		return nil, err
 	}
-	zoneTrim := strings.TrimSuffix(strings.TrimSpace(zone), ".")
+	zoneTrim, err := trimZone(zone)
+	if err != nil {
+		return nil, err
+	}
 	if zoneTrim == "" {
 		return nil, fmt.Errorf("empty zone")
 	}
 
-	for _, r := range recs {
-		fqdn, txt, ttl, err := ensureAcmeTXT(zoneTrim, r)
-		if err != nil {
-			return nil, err
+	groups, err := groupTXTChanges(zoneTrim, recs, policy)
+	if err != nil {
+		return nil, err
+	}
+	for _, group := range groups {
+		for _, c := range group {
+			if err := p.ValueValidation.check(c.fqdn, c.txt); err != nil {
+				return nil, err
+			}
 		}
-		txt = normalizeTXT(txt)
+	}
+
+	ttlPolicy := p.TTLPolicy.forZone(zoneTrim)
+	out := make([]libdns.Record, len(recs))
+	// created collects the records of rrsets already written, returned
+	// with the error if a later step fails.
+	var created []libdns.Record
+
+	// One read and one PATCH per rrset, so e.g. the example.com and
+	// *.example.com challenges, which share a name, go out together.
+	for _, group := range groups {
+		fqdn := group[0].fqdn
 
 		// Check existing rrset values
 		existing, existingTTL, err := p.getExistingTXTValues(ctx, zoneTrim, fqdn)
 		if err != nil {
-			return nil, err
+			return created, err
 		}
 
-		if len(existing) == 0 {
+		rrsetExists := len(existing) > 0
+		var added []string
+		for _, c := range group {
+			if _, ok := existing[c.txt]; ok {
+				continue
+			}
+			existing[c.txt] = formatTXT(c.txt)
+			added = append(added, c.txt)
+		}
+
+		tp := ttlPolicy
+		if hasLeadingLabel(libdns.RelativeName(fqdn, zoneTrim+"."), persistLabel) {
+			tp = p.persistTTLPolicy()
+		}
+		ttl := tp.effective(group[0].ttl)
+		if rrsetExists && existingTTL > 0 && !tp.OverrideExisting {
+			ttl = tp.clamp(timeSeconds(existingTTL))
+		}
+		for _, c := range group {
+			out[c.index] = p.txtRecord(zoneTrim, fqdn, c.txt, ttl)
+		}
+		if len(added) == 0 && (!rrsetExists || ttl == existingTTL) {
+			continue
+		}
+
+		var sets []UpdateRRSet
+		if !rrsetExists {
 			// rrset doesn't exist -> ADD
-			sets := []UpdateRRSet{{
+			records := make([]Record, 0, len(added))
+			for _, v := range added {
+				records = append(records, Record{Content: existing[v], Disabled: false})
+			}
+			sets = []UpdateRRSet{{
 				Name:       fqdn,
 				Type:       "TXT",
 				TTL:        ttl,
 				ChangeType: changeTypeAdd,
-				Records: []Record{{
-					Content:  txt,
-					Disabled: false,
-				}},
+				Records:    records,
 			}}
-
-			if _, err := p.client.PatchRRsets(ctx, zoneTrim, sets); err != nil {
-				return nil, err
+		} else {
+			// rrset exists -> UPDATE with merged set.
+			// Resend existing values exactly as the API stored them.
+			merged := make([]Record, 0, len(existing))
+			for _, content := range existing {
+				merged = append(merged, Record{Content: content, Disabled: false})
 			}
-			continue
-		}
 
-		// rrset exists -> UPDATE with merged set
-		existing[txt] = true
-
-		merged := make([]Record, 0, len(existing))
-		for v := range existing {
-			merged = append(merged, Record{Content: v, Disabled: false})
+			sets = []UpdateRRSet{{
+				Name:       fqdn,
+				Type:       "TXT",
+				TTL:        ttl,
+				ChangeType: changeTypeUpdate,
+				Records:    merged,
+			}}
 		}
 
-		ttlToUse := ttl
-		if existingTTL > 0 {
-			ttlToUse = existingTTL
+		if err := p.patch(ctx, zoneTrim, sets); err != nil {
+			return created, err
 		}
-
-		sets := []UpdateRRSet{{
-			Name:       fqdn,
-			Type:       "TXT",
-			TTL:        ttlToUse,
-			ChangeType: changeTypeUpdate,
-			Records:    merged,
-		}}
-
-		if _, err := p.client.PatchRRsets(ctx, zoneTrim, sets); err != nil {
-			return nil, err
+		for _, c := range group {
+			created = append(created, out[c.index])
+		}
+		for _, v := range added {
+			if err := p.trackOwned(ctx, zoneTrim, fqdn, v); err != nil {
+				return created, err
+			}
 		}
 	}
 
-	return recs, nil
+	return out, nil
 }
 
+// DeleteRecords removes exactly the given TXT values. It returns the records
+// that were actually removed, as stored; values not present are skipped.
 func (p *Provider) DeleteRecords(ctx context.Context, zone string, recs []libdns.Record) ([]libdns.Record, error) {
+	return p.deleteRecords(ctx, zone, recs, p.NamePolicy)
+}
+
+func (p *Provider) deleteRecords(ctx context.Context, zone string, recs []libdns.Record, policy NamePolicy) ([]libdns.Record, error) {
 	if err := p.init(); err != nil {
 		return nil, err
 	}
-	zoneTrim := strings.TrimSuffix(strings.TrimSpace(zone), ".")
+	zoneTrim, err := trimZone(zone)
+	if err != nil {
+		return nil, err
+	}
 	if zoneTrim == "" {
 		return nil, fmt.Errorf("empty zone")
 	}
 
-	for _, r := range recs {
-		fqdn, txt, ttl, err := ensureAcmeTXT(zoneTrim, r)
+	groups, err := groupTXTChanges(zoneTrim, recs, policy)
+	if err != nil {
+		return nil, err
+	}
+
+	ttlPolicy := p.TTLPolicy.forZone(zoneTrim)
+	deleted := make([]libdns.Record, 0, len(recs))
+	for _, group := range groups {
+		fqdn := group[0].fqdn
+
+		// Match values against the rrset by decoded text, and delete them
+		// using the content exactly as the API stored it.
+		existing, existingTTL, err := p.getExistingTXTValues(ctx, zoneTrim, fqdn)
 		if err != nil {
-			return nil, err
+			return deleted, err
+		}
+		ttl := ttlPolicy.effective(group[0].ttl)
+		if existingTTL > 0 {
+			ttl = existingTTL
+		}
+
+		var (
+			records []Record
+			matched []txtChange
+		)
+		for _, c := range group {
+			if p.OwnedOnly {
+				_, owned, err := p.ownership().Get(ctx, zoneTrim, fqdn, c.txt)
+				if err != nil {
+					return deleted, fmt.Errorf("ownership store: %w", err)
+				}
+				if !owned {
+					continue
+				}
+			}
+
+			content, found := existing[c.txt]
+			if !found {
+				// Already gone; just forget it.
+				if err := p.ownership().Delete(ctx, zoneTrim, fqdn, c.txt); err != nil {
+					return deleted, fmt.Errorf("ownership store: %w", err)
+				}
+				continue
+			}
+			delete(existing, c.txt)
+			records = append(records, Record{Content: content, Disabled: false})
+			matched = append(matched, c)
+		}
+		if len(records) == 0 {
+			continue
 		}
-		txt = normalizeTXT(txt)
 
 		// IMPORTANT:
-		// Remove exactly this TXT value using changetype=delete with records payload.
+		// Remove exactly these TXT values using changetype=delete with records payload.
 		// Do NOT rely on changetype=update to remove a missing value (it doesn't work here).
 		sets := []UpdateRRSet{{
 			Name:       fqdn,
 			Type:       "TXT",
 			TTL:        ttl,
 			ChangeType: changeTypeDelete,
-			Records: []Record{{
-				Content:  txt,
-				Disabled: false,
-			}},
+			Records:    records,
 		}}
 
-		if _, err := p.client.PatchRRsets(ctx, zoneTrim, sets); err != nil {
-			return nil, err
+		if err := p.patch(ctx, zoneTrim, sets); err != nil {
+			return deleted, err
+		}
+		for _, c := range matched {
+			if err := p.ownership().Delete(ctx, zoneTrim, fqdn, c.txt); err != nil {
+				return deleted, fmt.Errorf("ownership store: %w", err)
+			}
+			deleted = append(deleted, p.txtRecord(zoneTrim, fqdn, c.txt, ttl))
 		}
 	}
 
-	return recs, nil
+	return deleted, nil
 }
 
 // SetRecords is defined by libdns; for ACME usage you usually don't need it.
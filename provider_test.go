@@ -0,0 +1,259 @@
+package rcodezeroacme
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+
+	"github.com/kagescode/libdns-rcodezeroacme/internal/apitest"
+)
+
+// fakeACME is an in-memory stand-in for the ACME rrsets endpoint. It stores
+// TXT content the way the real API does (quoted presentation format).
+type fakeACME struct {
+	mu      sync.Mutex
+	rrsets  map[string]*RRSet // by lower-case name
+	patches [][]UpdateRRSet
+}
+
+func newFakeACME() *fakeACME { return &fakeACME{rrsets: map[string]*RRSet{}} }
+
+func (f *fakeACME) Do(req *http.Request) (*http.Response, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if req.Method == http.MethodGet {
+		out := GetRRsetsResponse{CurrentPage: 1, LastPage: 1}
+		names := make([]string, 0, len(f.rrsets))
+		for n := range f.rrsets {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		for _, n := range names {
+			out.Data = append(out.Data, *f.rrsets[n])
+		}
+		raw, _ := json.Marshal(out)
+		return apitest.JSONResponse(200, string(raw)), nil
+	}
+
+	var sets []UpdateRRSet
+	if err := json.NewDecoder(req.Body).Decode(&sets); err != nil {
+		return apitest.JSONResponse(400, `{"status":"failed","message":"bad json"}`), nil
+	}
+	f.patches = append(f.patches, sets)
+
+	for _, s := range sets {
+		key := strings.ToLower(s.Name)
+		switch s.ChangeType {
+		case changeTypeAdd, changeTypeUpdate:
+			rr := &RRSet{Name: s.Name, Type: s.Type, TTL: s.TTL}
+			for _, r := range s.Records {
+				rr.Records = append(rr.Records, Record{Content: formatTXT(decodeTXT(r.Content))})
+			}
+			f.rrsets[key] = rr
+		case changeTypeDelete:
+			rr := f.rrsets[key]
+			if rr == nil {
+				continue
+			}
+			var keep []Record
+			for _, have := range rr.Records {
+				drop := false
+				for _, r := range s.Records {
+					if have.Content == r.Content {
+						drop = true
+					}
+				}
+				if !drop {
+					keep = append(keep, have)
+				}
+			}
+			rr.Records = keep
+			if len(keep) == 0 {
+				delete(f.rrsets, key)
+			}
+		}
+	}
+	return apitest.JSONResponse(200, `{"status":"ok","message":""}`), nil
+}
+
+func (f *fakeACME) values(name string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	rr := f.rrsets[strings.ToLower(name)]
+	if rr == nil {
+		return nil
+	}
+	var out []string
+	for _, r := range rr.Records {
+		out = append(out, decodeTXT(r.Content))
+	}
+	sort.Strings(out)
+	return out
+}
+
+func TestProvider_AppendDeleteEscapedValues(t *testing.T) {
+	api := newFakeACME()
+	p := &Provider{APIToken: apitest.Token, HTTPClient: api}
+	ctx := context.Background()
+
+	a := libdns.TXT{Name: "_acme-challenge.servera", Text: `has "quotes" and \ spaces`, TTL: time.Minute}
+	b := libdns.TXT{Name: "_acme-challenge.servera", Text: "plain", TTL: time.Minute}
+	other := libdns.TXT{Name: "_acme-challenge.serverb", Text: "other", TTL: time.Minute}
+
+	for _, r := range []libdns.TXT{a, b, other} {
+		if _, err := p.AppendRecords(ctx, "example.com.", []libdns.Record{r}); err != nil {
+			t.Fatalf("AppendRecords: %v", err)
+		}
+	}
+
+	got := api.values("_acme-challenge.servera.example.com.")
+	want := []string{`has "quotes" and \ spaces`, "plain"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("servera values = %q, want %q", got, want)
+	}
+	if got := api.values("_acme-challenge.serverb.example.com."); len(got) != 1 || got[0] != "other" {
+		t.Fatalf("serverb values = %q", got)
+	}
+
+	if _, err := p.DeleteRecords(ctx, "example.com.", []libdns.Record{a}); err != nil {
+		t.Fatalf("DeleteRecords: %v", err)
+	}
+	if got := api.values("_acme-challenge.servera.example.com."); len(got) != 1 || got[0] != "plain" {
+		t.Fatalf("after delete servera values = %q", got)
+	}
+
+	recs, err := p.GetRecords(ctx, "example.com.")
+	if err != nil {
+		t.Fatalf("GetRecords: %v", err)
+	}
+	if len(recs) != 2 {
+		t.Fatalf("GetRecords returned %d records: %v", len(recs), recs)
+	}
+}
+
+func TestProvider_WildcardAndApexShareOnePatch(t *testing.T) {
+	api := newFakeACME()
+	p := &Provider{APIToken: apitest.Token, HTTPClient: api}
+	ctx := context.Background()
+
+	apex := ChallengeTXT("example.com.", "example.com", "tok1", "thumb")
+	wild := libdns.TXT{Name: "_acme-challenge.*", Text: DNS01Value("tok2.thumb")}
+
+	if _, err := p.AppendRecords(ctx, "example.com.", []libdns.Record{apex, wild}); err != nil {
+		t.Fatalf("AppendRecords: %v", err)
+	}
+	if len(api.patches) != 1 || len(api.patches[0]) != 1 || len(api.patches[0][0].Records) != 2 {
+		t.Fatalf("expected one PATCH with two values, got %+v", api.patches)
+	}
+	if got := api.values("_acme-challenge.example.com."); len(got) != 2 {
+		t.Fatalf("values = %q", got)
+	}
+
+	deleted, err := p.DeleteRecords(ctx, "example.com.", []libdns.Record{apex, wild})
+	if err != nil || len(deleted) != 2 {
+		t.Fatalf("DeleteRecords = %v, %v", deleted, err)
+	}
+	if len(api.patches) != 2 || api.patches[1][0].ChangeType != changeTypeDelete {
+		t.Fatalf("expected a single delete PATCH, got %+v", api.patches)
+	}
+	if got := api.values("_acme-challenge.example.com."); len(got) != 0 {
+		t.Errorf("values left: %q", got)
+	}
+}
+
+func TestProvider_ReturnsRecordsAsStored(t *testing.T) {
+	api := newFakeACME()
+	p := &Provider{APIToken: apitest.Token, HTTPClient: api}
+	ctx := context.Background()
+
+	in := []libdns.Record{
+		libdns.TXT{Name: "_acme-challenge.*.www", Text: `"quoted"`},
+		libdns.RR{Name: "_acme-challenge.mail.example.com.", Type: "TXT", Data: " padded ", TTL: 2 * time.Minute},
+	}
+	got, err := p.AppendRecords(ctx, "example.com.", in)
+	if err != nil {
+		t.Fatalf("AppendRecords: %v", err)
+	}
+
+	stored, err := p.GetRecords(ctx, "example.com.")
+	if err != nil {
+		t.Fatalf("GetRecords: %v", err)
+	}
+	byName := map[string]libdns.Record{}
+	for _, r := range stored {
+		byName[r.RR().Name] = r
+	}
+
+	want := []libdns.TXT{
+		{Name: "_acme-challenge.www", Text: "quoted", TTL: time.Minute},
+		{Name: "_acme-challenge.mail", Text: "padded", TTL: 2 * time.Minute},
+	}
+	for i, w := range want {
+		if got[i] != w {
+			t.Errorf("AppendRecords[%d] = %+v, want %+v", i, got[i], w)
+		}
+		if s := byName[w.Name]; s != w {
+			t.Errorf("GetRecords has %+v, want %+v", s, w)
+		}
+	}
+
+	deleted, err := p.DeleteRecords(ctx, "example.com.", in)
+	if err != nil || len(deleted) != 2 || deleted[0] != want[0] || deleted[1] != want[1] {
+		t.Fatalf("DeleteRecords = %+v, %v", deleted, err)
+	}
+}
+
+func TestProvider_OwnedOnlyKeepsUnownedValues(t *testing.T) {
+	api := newFakeACME()
+	other := &Provider{APIToken: apitest.Token, HTTPClient: api}
+	p := &Provider{APIToken: apitest.Token, HTTPClient: api, OwnedOnly: true}
+	ctx := context.Background()
+
+	theirs := libdns.TXT{Name: "_acme-challenge", Text: "theirs"}
+	mine := libdns.TXT{Name: "_acme-challenge", Text: "mine"}
+	if _, err := other.AppendRecords(ctx, "example.com.", []libdns.Record{theirs}); err != nil {
+		t.Fatalf("AppendRecords: %v", err)
+	}
+	if _, err := p.AppendRecords(ctx, "example.com.", []libdns.Record{mine}); err != nil {
+		t.Fatalf("AppendRecords: %v", err)
+	}
+
+	deleted, err := p.DeleteRecords(ctx, "example.com.", []libdns.Record{theirs, mine})
+	if err != nil {
+		t.Fatalf("DeleteRecords: %v", err)
+	}
+	if len(deleted) != 1 || deleted[0].(libdns.TXT).Text != "mine" {
+		t.Fatalf("DeleteRecords = %+v, want only the owned value", deleted)
+	}
+	if got := api.values("_acme-challenge.example.com."); len(got) != 1 || got[0] != "theirs" {
+		t.Fatalf("values = %q, want the unowned value to survive", got)
+	}
+}
+
+// failingStore refuses every write.
+type failingStore struct{ MemoryStore }
+
+func (*failingStore) Put(context.Context, OwnedValue) error { return errors.New("disk full") }
+
+func TestProvider_AppendReturnsCreatedOnTrackingFailure(t *testing.T) {
+	api := newFakeACME()
+	p := &Provider{APIToken: apitest.Token, HTTPClient: api, Store: &failingStore{}}
+
+	rec := libdns.TXT{Name: "_acme-challenge", Text: "v", TTL: time.Minute}
+	got, err := p.AppendRecords(context.Background(), "example.com.", []libdns.Record{rec})
+	if err == nil || !strings.Contains(err.Error(), "disk full") {
+		t.Fatalf("expected tracking error, got %v", err)
+	}
+	if len(got) != 1 || got[0] != rec {
+		t.Fatalf("AppendRecords = %+v, want the created record", got)
+	}
+}
@@ -0,0 +1,139 @@
+package rcodezeroacme
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+// PurgePolicy controls which challenge values PurgeStale removes.
+type PurgePolicy struct {
+	// MaxAge marks values this provider created more than MaxAge ago as
+	// stale. Zero disables age-based purging.
+	MaxAge time.Duration
+
+	// PurgeUntracked also removes _acme-challenge values that are not in
+	// the provider's Store (e.g. left behind by a crashed issuer before a
+	// restart). Leave false if other systems write challenges to the zone.
+	PurgeUntracked bool
+
+	// DryRun reports what would be removed without deleting anything.
+	DryRun bool
+}
+
+// PurgeResult lists the challenge values removed (or, with DryRun, that
+// would have been removed) from a zone.
+type PurgeResult struct {
+	Zone    string
+	Removed []libdns.TXT
+
+	// Serial is the zone serial containing the removals, if TrackSerial is
+	// set and it could be determined.
+	Serial uint32
+}
+
+// PurgeStale deletes stale _acme-challenge TXT values from zone according to
+// policy. Each rrset gets one changetype=delete naming its stale values, so
+// other values in the same rrset are kept.
+func (p *Provider) PurgeStale(ctx context.Context, zone string, policy PurgePolicy) (PurgeResult, error) {
+	if err := p.init(); err != nil {
+		return PurgeResult{}, err
+	}
+	zoneTrim, err := trimZone(zone)
+	if err != nil {
+		return PurgeResult{}, err
+	}
+	if zoneTrim == "" {
+		return PurgeResult{}, fmt.Errorf("empty zone")
+	}
+	res := PurgeResult{Zone: zoneTrim}
+
+	rrsets, err := p.listAcmeTXT(ctx, zoneTrim)
+	if err != nil {
+		return res, err
+	}
+
+	now := time.Now()
+	for _, rrset := range rrsets {
+		var (
+			stale   []string
+			records []Record // stale values as stored by the API
+		)
+		for _, rec := range rrset.Records {
+			if rec.Disabled {
+				continue
+			}
+			value := decodeTXT(rec.Content)
+			owned, tracked, err := p.ownership().Get(ctx, zoneTrim, rrset.Name, value)
+			if err != nil {
+				return res, fmt.Errorf("ownership store: %w", err)
+			}
+			switch {
+			case tracked && policy.MaxAge > 0 && now.Sub(owned.Created) > policy.MaxAge:
+			case !tracked && policy.PurgeUntracked:
+			default:
+				continue
+			}
+			stale = append(stale, value)
+			records = append(records, Record{Content: rec.Content})
+		}
+		if len(stale) == 0 {
+			continue
+		}
+
+		if !policy.DryRun {
+			sets := []UpdateRRSet{{
+				Name:       rrset.Name,
+				Type:       "TXT",
+				TTL:        rrset.TTL,
+				ChangeType: changeTypeDelete,
+				Records:    records,
+			}}
+			if err := p.patch(ctx, zoneTrim, sets); err != nil {
+				return res, err
+			}
+		}
+
+		for _, value := range stale {
+			if !policy.DryRun {
+				if err := p.ownership().Delete(ctx, zoneTrim, rrset.Name, value); err != nil {
+					return res, fmt.Errorf("ownership store: %w", err)
+				}
+			}
+			res.Removed = append(res.Removed, p.txtRecord(zoneTrim, rrset.Name, value, rrset.TTL))
+		}
+	}
+
+	if len(res.Removed) > 0 && !policy.DryRun {
+		res.Serial, _ = p.LastSerial(zoneTrim)
+	}
+	return res, nil
+}
+
+// RunPurger calls PurgeStale for every zone each interval until ctx is
+// done. report, if non-nil, receives the outcome of every sweep.
+func (p *Provider) RunPurger(ctx context.Context, interval time.Duration, policy PurgePolicy, report func(PurgeResult, error), zones ...string) error {
+	if interval <= 0 {
+		return fmt.Errorf("purger: interval must be positive")
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for _, zone := range zones {
+			res, err := p.PurgeStale(ctx, zone, policy)
+			if report != nil {
+				report(res, err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
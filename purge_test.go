@@ -0,0 +1,114 @@
+package rcodezeroacme
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kagescode/libdns-rcodezeroacme/internal/apitest"
+)
+
+func TestPurgeStale_OnlyRemovesStaleValues(t *testing.T) {
+	var patched []UpdateRRSet
+	hc := fakeHTTP(func(req *http.Request) (*http.Response, error) {
+		if req.Method == http.MethodPatch {
+			if err := json.NewDecoder(req.Body).Decode(&patched); err != nil {
+				t.Fatalf("decode patch: %v", err)
+			}
+			return apitest.JSONResponse(200, `{"status":"ok","message":""}`), nil
+		}
+		return apitest.JSONResponse(200, `{"data":[{"name":"_acme-challenge.example.com.","type":"TXT","ttl":60,"records":[
+			{"content":"\"old\""},{"content":"\"fresh\""},{"content":"\"foreign\""}]}],"last_page":1}`), nil
+	})
+
+	store := &MemoryStore{}
+	ctx := context.Background()
+	_ = store.Put(ctx, OwnedValue{Zone: "example.com", Name: "_acme-challenge.example.com.", Value: "old", Created: time.Now().Add(-2 * time.Hour)})
+	_ = store.Put(ctx, OwnedValue{Zone: "example.com", Name: "_acme-challenge.example.com", Value: "fresh", Created: time.Now()})
+	p := &Provider{APIToken: apitest.Token, HTTPClient: hc, Store: store}
+
+	res, err := p.PurgeStale(ctx, "example.com.", PurgePolicy{MaxAge: time.Hour})
+	if err != nil {
+		t.Fatalf("PurgeStale: %v", err)
+	}
+	if len(res.Removed) != 1 || res.Removed[0].Text != "old" || res.Removed[0].Name != "_acme-challenge" {
+		t.Fatalf("unexpected removals: %+v", res.Removed)
+	}
+	if len(patched) != 1 || patched[0].ChangeType != changeTypeDelete ||
+		len(patched[0].Records) != 1 || patched[0].Records[0].Content != `"old"` {
+		t.Fatalf("unexpected patch payload: %+v", patched)
+	}
+	if _, ok, _ := store.Get(ctx, "example.com", "_acme-challenge.example.com", "old"); ok {
+		t.Errorf("purged value still tracked")
+	}
+}
+
+func TestPurgeStale_UntrackedAndDryRun(t *testing.T) {
+	api := newFakeACME()
+	api.rrsets["_acme-challenge.example.com."] = &RRSet{
+		Name: "_acme-challenge.example.com.", Type: "TXT", TTL: 60,
+		Records: []Record{{Content: `"mine"`}, {Content: `"leftover"`}},
+	}
+	store := &MemoryStore{}
+	ctx := context.Background()
+	_ = store.Put(ctx, OwnedValue{Zone: "example.com", Name: "_acme-challenge.example.com.", Value: "mine", Created: time.Now()})
+	p := &Provider{APIToken: apitest.Token, HTTPClient: api, Store: store}
+
+	// Without PurgeUntracked a value missing from the store is not stale.
+	res, err := p.PurgeStale(ctx, "example.com.", PurgePolicy{MaxAge: time.Hour})
+	if err != nil || len(res.Removed) != 0 {
+		t.Fatalf("PurgeStale = %+v, %v; want nothing removed", res, err)
+	}
+
+	res, err = p.PurgeStale(ctx, "example.com.", PurgePolicy{PurgeUntracked: true, DryRun: true})
+	if err != nil || len(res.Removed) != 1 || res.Removed[0].Text != "leftover" {
+		t.Fatalf("dry run = %+v, %v", res, err)
+	}
+	if len(api.patches) != 0 || len(api.values("_acme-challenge.example.com.")) != 2 {
+		t.Fatalf("dry run changed the zone: %+v", api.patches)
+	}
+
+	res, err = p.PurgeStale(ctx, "example.com.", PurgePolicy{PurgeUntracked: true})
+	if err != nil || len(res.Removed) != 1 || res.Removed[0].Text != "leftover" {
+		t.Fatalf("PurgeStale = %+v, %v", res, err)
+	}
+	if got := api.values("_acme-challenge.example.com."); len(got) != 1 || got[0] != "mine" {
+		t.Errorf("values = %q, want the tracked value kept", got)
+	}
+}
+
+func TestRunPurger_SweepsUntilCancelled(t *testing.T) {
+	api := newFakeACME()
+	p := &Provider{APIToken: apitest.Token, HTTPClient: api}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var sweeps []string
+	report := func(res PurgeResult, err error) {
+		if ctx.Err() != nil {
+			return // a tick raced the cancellation
+		}
+		if err != nil {
+			t.Errorf("sweep: %v", err)
+		}
+		sweeps = append(sweeps, res.Zone)
+		if len(sweeps) == 4 {
+			cancel()
+		}
+	}
+
+	err := p.RunPurger(ctx, time.Millisecond, PurgePolicy{PurgeUntracked: true}, report, "a.example", "b.example")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("RunPurger = %v, want context.Canceled", err)
+	}
+	if strings.Join(sweeps, ",") != "a.example,b.example,a.example,b.example" {
+		t.Errorf("sweeps = %q", sweeps)
+	}
+
+	if err := p.RunPurger(context.Background(), 0, PurgePolicy{}, nil); err == nil {
+		t.Errorf("expected error for non-positive interval")
+	}
+}
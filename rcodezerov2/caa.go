@@ -0,0 +1,306 @@
+package rcodezerov2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/libdns/libdns"
+
+	"github.com/kagescode/libdns-rcodezeroacme/internal/idn"
+)
+
+// caaCritical is the issuer critical flag (RFC 8659 section 4.1).
+const caaCritical = 128
+
+// ErrCAABlocked is wrapped by CheckCAA errors when the zone's CAA policy
+// would make the CA refuse the issuance.
+var ErrCAABlocked = errors.New("CAA policy blocks issuance")
+
+// CAAValue is a parsed issue or issuewild property value:
+// "letsencrypt.org; accounturi=https://...; validationmethods=dns-01".
+// An empty Issuer (value ";") forbids issuance.
+type CAAValue struct {
+	Issuer string
+	Params map[string]string
+}
+
+// ParseCAAValue parses an issue or issuewild property value.
+func ParseCAAValue(value string) (CAAValue, error) {
+	parts := strings.Split(value, ";")
+	v := CAAValue{Issuer: strings.ToLower(strings.TrimSpace(parts[0]))}
+	if v.Issuer != "" && !isDomainName(v.Issuer) {
+		return CAAValue{}, fmt.Errorf("invalid CAA issuer %q", v.Issuer)
+	}
+
+	for _, p := range parts[1:] {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		key, val, ok := strings.Cut(p, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" || strings.IndexFunc(key, func(r rune) bool { return !isAlnum(r) }) >= 0 {
+			return CAAValue{}, fmt.Errorf("invalid CAA parameter %q", p)
+		}
+		if v.Params == nil {
+			v.Params = map[string]string{}
+		}
+		v.Params[strings.ToLower(key)] = strings.TrimSpace(val)
+	}
+	return v, nil
+}
+
+// String formats v as a property value.
+func (v CAAValue) String() string {
+	if len(v.Params) == 0 {
+		if v.Issuer == "" {
+			return ";"
+		}
+		return v.Issuer
+	}
+	out := v.Issuer
+	// Keep the well-known parameters first and in a stable order.
+	for _, k := range caaParamOrder(v.Params) {
+		out += "; " + k + "=" + v.Params[k]
+	}
+	return out
+}
+
+// ValidateCAA checks CAA records before they are written: known tags must
+// carry well-formed values, and accounturi and validationmethods
+// parameters must be usable by an ACME CA.
+func ValidateCAA(recs []libdns.CAA) error {
+	for _, r := range recs {
+		tag := strings.ToLower(r.Tag)
+		if !isCAATag(tag) {
+			return fmt.Errorf("CAA %q: invalid tag %q", r.Name, r.Tag)
+		}
+		switch tag {
+		case "issue", "issuewild":
+			v, err := ParseCAAValue(r.Value)
+			if err != nil {
+				return fmt.Errorf("CAA %q %s: %w", r.Name, tag, err)
+			}
+			if u, ok := v.Params["accounturi"]; ok {
+				if pu, err := url.Parse(u); err != nil || pu.Scheme != "https" || pu.Host == "" {
+					return fmt.Errorf("CAA %q %s: accounturi %q is not an https URL", r.Name, tag, u)
+				}
+			}
+			if m, ok := v.Params["validationmethods"]; ok {
+				for _, method := range strings.Split(m, ",") {
+					if method == "" || strings.IndexFunc(method, func(r rune) bool { return !isAlnum(r) && r != '-' }) >= 0 {
+						return fmt.Errorf("CAA %q %s: invalid validation method %q", r.Name, tag, method)
+					}
+				}
+			}
+		case "iodef":
+			u, err := url.Parse(r.Value)
+			if err != nil || (u.Scheme != "mailto" && u.Scheme != "http" && u.Scheme != "https") {
+				return fmt.Errorf("CAA %q iodef: %q is not a mailto, http or https URL", r.Name, r.Value)
+			}
+		}
+	}
+	return nil
+}
+
+// GetCAA returns the CAA records at name (relative to zone, "@" for the
+// apex).
+func (p *Provider) GetCAA(ctx context.Context, zone, name string) ([]libdns.CAA, error) {
+	zoneTrim, rrsets, err := p.rrsets(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+	fqdn, err := absoluteName(name, zoneTrim)
+	if err != nil {
+		return nil, err
+	}
+	return caaAt(zoneTrim, indexRRsets(zoneTrim, rrsets), fqdn), nil
+}
+
+// SetCAA validates recs and replaces the CAA rrset at name with them. An
+// empty recs deletes the rrset.
+func (p *Provider) SetCAA(ctx context.Context, zone, name string, recs []libdns.CAA) error {
+	if err := ValidateCAA(recs); err != nil {
+		return err
+	}
+	if len(recs) == 0 {
+		_, err := p.DeleteRecords(ctx, zone, []libdns.Record{libdns.RR{Name: name, Type: "CAA"}})
+		return err
+	}
+
+	out := make([]libdns.Record, 0, len(recs))
+	for _, r := range recs {
+		r.Name = name
+		out = append(out, r)
+	}
+	_, err := p.SetRecords(ctx, zone, out)
+	return err
+}
+
+// Issuance describes a certificate request for CheckCAA.
+type Issuance struct {
+	// Domain is the name on the certificate, "*.example.com" for a
+	// wildcard.
+	Domain string
+
+	// IssuerDomain is the CA's CAA identifier, e.g. "letsencrypt.org".
+	IssuerDomain string
+
+	// AccountURI is the ACME account URL, checked against accounturi
+	// parameters. Leave empty to skip that check.
+	AccountURI string
+
+	// ValidationMethod is the challenge type, e.g. "dns-01", checked
+	// against validationmethods parameters. Leave empty to skip that check.
+	ValidationMethod string
+}
+
+// CheckCAA reports whether the CAA records in zone would let the CA issue
+// iss, following the RFC 8659 tree climb from iss.Domain up to the zone
+// apex. It returns an error wrapping ErrCAABlocked if issuance would be
+// refused. CAA records in parent zones are not visible through the API and
+// are not checked.
+func (p *Provider) CheckCAA(ctx context.Context, zone string, iss Issuance) error {
+	zoneTrim, rrsets, err := p.rrsets(ctx, zone)
+	if err != nil {
+		return err
+	}
+	index := indexRRsets(zoneTrim, rrsets)
+
+	domain, err := idn.ToASCII(strings.ToLower(strings.TrimSuffix(strings.TrimSpace(iss.Domain), ".")))
+	if err != nil {
+		return fmt.Errorf("caa: %w", err)
+	}
+	wildcard := strings.HasPrefix(domain, "*.")
+	domain = strings.TrimPrefix(domain, "*.")
+	zoneLower := strings.ToLower(zoneTrim)
+	if domain != zoneLower && !strings.HasSuffix(domain, "."+zoneLower) {
+		return fmt.Errorf("caa: %s is not in zone %s", iss.Domain, zoneTrim)
+	}
+
+	// The relevant rrset is the first non-empty one climbing to the apex.
+	var relevant []libdns.CAA
+	for name := domain; ; {
+		if relevant = caaAt(zoneTrim, index, name+"."); len(relevant) > 0 {
+			break
+		}
+		if name == zoneLower {
+			return nil
+		}
+		_, name, _ = strings.Cut(name, ".")
+	}
+
+	return checkIssuance(relevant, iss, wildcard)
+}
+
+func checkIssuance(set []libdns.CAA, iss Issuance, wildcard bool) error {
+	var issue, issueWild []libdns.CAA
+	for _, r := range set {
+		switch strings.ToLower(r.Tag) {
+		case "issue":
+			issue = append(issue, r)
+		case "issuewild":
+			issueWild = append(issueWild, r)
+		case "iodef", "issuemail", "issuevmc", "contactemail", "contactphone":
+		default:
+			if r.Flags&caaCritical != 0 {
+				return fmt.Errorf("%w: unknown critical property %q at %s", ErrCAABlocked, r.Tag, r.Name)
+			}
+		}
+	}
+
+	props, tag := issue, "issue"
+	if wildcard && len(issueWild) > 0 {
+		props, tag = issueWild, "issuewild"
+	}
+	if len(props) == 0 {
+		return nil
+	}
+
+	issuer := strings.ToLower(strings.TrimSuffix(iss.IssuerDomain, "."))
+	var reasons []string
+	for _, r := range props {
+		v, err := ParseCAAValue(r.Value)
+		if err != nil {
+			reasons = append(reasons, fmt.Sprintf("unparseable %s %q", tag, r.Value))
+			continue
+		}
+		if v.Issuer != issuer {
+			continue
+		}
+		if u, ok := v.Params["accounturi"]; ok && iss.AccountURI != "" && u != iss.AccountURI {
+			reasons = append(reasons, fmt.Sprintf("accounturi is %s", u))
+			continue
+		}
+		if m, ok := v.Params["validationmethods"]; ok && iss.ValidationMethod != "" && !containsFold(strings.Split(m, ","), iss.ValidationMethod) {
+			reasons = append(reasons, fmt.Sprintf("validationmethods is %s", m))
+			continue
+		}
+		return nil
+	}
+
+	if len(reasons) == 0 {
+		return fmt.Errorf("%w: %s at %s does not authorize %s", ErrCAABlocked, tag, props[0].Name, iss.IssuerDomain)
+	}
+	return fmt.Errorf("%w: %s at %s for %s: %s", ErrCAABlocked, tag, props[0].Name, iss.IssuerDomain, strings.Join(reasons, "; "))
+}
+
+// caaAt returns the CAA records of the rrset at the absolute name fqdn.
+func caaAt(zoneTrim string, index map[string]RRSet, fqdn string) []libdns.CAA {
+	rrset, ok := index[rrsetKey(fqdn, "CAA")]
+	if !ok {
+		return nil
+	}
+	var out []libdns.CAA
+	for _, rec := range rrset.Records {
+		if rec.Disabled {
+			continue
+		}
+		if r, ok := fromRecord(zoneTrim, rrset, rec).(libdns.CAA); ok {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+func caaParamOrder(params map[string]string) []string {
+	var keys, rest []string
+	for _, k := range []string{"accounturi", "validationmethods"} {
+		if _, ok := params[k]; ok {
+			keys = append(keys, k)
+		}
+	}
+	for k := range params {
+		if k != "accounturi" && k != "validationmethods" {
+			rest = append(rest, k)
+		}
+	}
+	sort.Strings(rest)
+	return append(keys, rest...)
+}
+
+func isDomainName(s string) bool {
+	for _, label := range strings.Split(s, ".") {
+		if label == "" || strings.IndexFunc(label, func(r rune) bool { return !isAlnum(r) && r != '-' }) >= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func isAlnum(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9'
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(strings.TrimSpace(v), s) {
+			return true
+		}
+	}
+	return false
+}
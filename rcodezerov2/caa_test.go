@@ -0,0 +1,123 @@
+package rcodezerov2
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/libdns/libdns"
+
+	"github.com/kagescode/libdns-rcodezeroacme/internal/apitest"
+)
+
+func TestParseCAAValue(t *testing.T) {
+	v, err := ParseCAAValue("LetsEncrypt.org; validationmethods=dns-01; accounturi=https://acme.example/acct/1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Issuer != "letsencrypt.org" || v.Params["validationmethods"] != "dns-01" || v.Params["accounturi"] != "https://acme.example/acct/1" {
+		t.Fatalf("ParseCAAValue = %+v", v)
+	}
+	if got, want := v.String(), "letsencrypt.org; accounturi=https://acme.example/acct/1; validationmethods=dns-01"; got != want {
+		t.Errorf("String = %q, want %q", got, want)
+	}
+
+	if v, err := ParseCAAValue(";"); err != nil || v.Issuer != "" || v.String() != ";" {
+		t.Errorf(`ParseCAAValue(";") = %+v, %v`, v, err)
+	}
+	for _, bad := range []string{"not a domain", "ca.example; =x", "ca.example; novalue"} {
+		if _, err := ParseCAAValue(bad); err == nil {
+			t.Errorf("ParseCAAValue(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestValidateCAA(t *testing.T) {
+	good := []libdns.CAA{
+		{Name: "@", Tag: "issue", Value: "letsencrypt.org; accounturi=https://acme-v02.api.letsencrypt.org/acme/acct/1; validationmethods=dns-01,http-01"},
+		{Name: "@", Tag: "issuewild", Value: ";"},
+		{Name: "@", Tag: "iodef", Value: "mailto:security@example.com"},
+	}
+	if err := ValidateCAA(good); err != nil {
+		t.Fatalf("ValidateCAA: %v", err)
+	}
+
+	for _, bad := range []libdns.CAA{
+		{Name: "@", Tag: "issue", Value: "ca.example; accounturi=http://insecure/acct"},
+		{Name: "@", Tag: "issue", Value: "ca.example; validationmethods=dns_01"},
+		{Name: "@", Tag: "iodef", Value: "security@example.com"},
+		{Name: "@", Tag: "is sue", Value: "ca.example"},
+	} {
+		if err := ValidateCAA([]libdns.CAA{bad}); err == nil {
+			t.Errorf("ValidateCAA(%+v) succeeded", bad)
+		}
+	}
+}
+
+func TestProvider_CheckCAA(t *testing.T) {
+	api := newFakeV2()
+	p := &Provider{APIToken: apitest.Token, HTTPClient: api}
+	ctx := context.Background()
+
+	err := p.SetCAA(ctx, "example.com", "@", []libdns.CAA{
+		{Tag: "issue", Value: "letsencrypt.org; validationmethods=dns-01; accounturi=https://acme.example/acct/1"},
+		{Tag: "issuewild", Value: ";"},
+	})
+	if err != nil {
+		t.Fatalf("SetCAA: %v", err)
+	}
+	if err := p.SetCAA(ctx, "example.com", "legacy", []libdns.CAA{{Tag: "issue", Value: "other-ca.example"}}); err != nil {
+		t.Fatalf("SetCAA: %v", err)
+	}
+	if got, err := p.GetCAA(ctx, "example.com", "@"); err != nil || len(got) != 2 {
+		t.Fatalf("GetCAA = %+v, %v", got, err)
+	}
+
+	le := Issuance{IssuerDomain: "letsencrypt.org", AccountURI: "https://acme.example/acct/1", ValidationMethod: "dns-01"}
+	tests := []struct {
+		domain  string
+		mutate  func(*Issuance)
+		blocked bool
+	}{
+		{domain: "www.example.com"},
+		{domain: "example.com."},
+		{domain: "*.example.com", blocked: true},
+		{domain: "a.legacy.example.com", blocked: true},
+		{domain: "www.example.com", mutate: func(i *Issuance) { i.ValidationMethod = "http-01" }, blocked: true},
+		{domain: "www.example.com", mutate: func(i *Issuance) { i.AccountURI = "https://acme.example/acct/2" }, blocked: true},
+		{domain: "www.example.com", mutate: func(i *Issuance) { i.IssuerDomain = "other-ca.example" }, blocked: true},
+		{domain: "www.legacy.example.com", mutate: func(i *Issuance) { i.IssuerDomain = "other-ca.example" }},
+	}
+	for _, tt := range tests {
+		iss := le
+		iss.Domain = tt.domain
+		if tt.mutate != nil {
+			tt.mutate(&iss)
+		}
+		err := p.CheckCAA(ctx, "example.com", iss)
+		if blocked := errors.Is(err, ErrCAABlocked); blocked != tt.blocked || (!tt.blocked && err != nil) {
+			t.Errorf("CheckCAA(%+v) = %v, want blocked=%v", iss, err, tt.blocked)
+		}
+	}
+
+	if err := p.CheckCAA(ctx, "example.com", Issuance{Domain: "other.org", IssuerDomain: "letsencrypt.org"}); err == nil || errors.Is(err, ErrCAABlocked) {
+		t.Errorf("CheckCAA outside the zone = %v", err)
+	}
+
+	if err := p.SetCAA(ctx, "example.com", "@", nil); err != nil {
+		t.Fatalf("SetCAA(nil): %v", err)
+	}
+	if err := p.CheckCAA(ctx, "example.com", Issuance{Domain: "*.example.com", IssuerDomain: "any.example"}); err != nil {
+		t.Errorf("CheckCAA without CAA records = %v", err)
+	}
+}
+
+func TestCheckIssuance_CriticalUnknownTag(t *testing.T) {
+	set := []libdns.CAA{
+		{Name: "@", Tag: "issue", Value: "ca.example"},
+		{Name: "@", Flags: caaCritical, Tag: "futuretag", Value: "x"},
+	}
+	if err := checkIssuance(set, Issuance{IssuerDomain: "ca.example"}, false); !errors.Is(err, ErrCAABlocked) {
+		t.Errorf("checkIssuance = %v, want ErrCAABlocked", err)
+	}
+}
@@ -0,0 +1,150 @@
+package rcodezerov2
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	rcodezeroacme "github.com/kagescode/libdns-rcodezeroacme"
+	"github.com/kagescode/libdns-rcodezeroacme/internal/transport"
+)
+
+const defaultBaseURL = "https://my.rcodezero.at"
+
+// Client talks to the RcodeZero v2 rrset endpoints.
+type Client struct {
+	tokens     rcodezeroacme.TokenSource
+	baseURL    *url.URL
+	httpClient rcodezeroacme.HTTPClient
+
+	// Retries is how often a failed GET is retried (network errors, 429,
+	// 502/503/504). Writes are never retried.
+	Retries int
+
+	// MaxResponseSize limits response bodies in bytes; 4 MiB if zero.
+	MaxResponseSize int64
+
+	// UserAgent replaces the default "libdns-rcodezeroacme/<version>".
+	// UserAgentSuffix is appended to it to identify the caller, e.g.
+	// "caddy/2.8".
+	UserAgent       string
+	UserAgentSuffix string
+
+	// Header holds extra headers sent with every request. Authorization
+	// and Content-Type cannot be overridden.
+	Header http.Header
+}
+
+func NewClient(apiToken, baseURL string, hc rcodezeroacme.HTTPClient) (*Client, error) {
+	if strings.TrimSpace(apiToken) == "" {
+		return nil, fmt.Errorf("APIToken is required")
+	}
+	return NewClientWithTokenSource(rcodezeroacme.StaticToken(apiToken), baseURL, hc)
+}
+
+// NewClientWithTokenSource is like NewClient but asks ts for the bearer
+// token on every request instead of using a fixed string.
+func NewClientWithTokenSource(ts rcodezeroacme.TokenSource, baseURL string, hc rcodezeroacme.HTTPClient) (*Client, error) {
+	if ts == nil {
+		return nil, fmt.Errorf("token source is required")
+	}
+	if baseURL == "" {
+		baseURL = defaultBaseURL
+	}
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base url: %w", err)
+	}
+	return &Client{
+		tokens:     ts,
+		baseURL:    u,
+		httpClient: hc,
+	}, nil
+}
+
+func (c *Client) do(req *http.Request, out any) error {
+	r := transport.Requester{
+		Token:           c.tokens.Token,
+		HTTP:            c.httpClient,
+		Retries:         c.Retries,
+		MaxResponseSize: c.MaxResponseSize,
+		UserAgent:       c.UserAgent,
+		UserAgentSuffix: c.UserAgentSuffix,
+		Header:          c.Header,
+		Prefix:          "rcodezero v2 http",
+	}
+	if err := r.Do(req, out); err != nil {
+		return err
+	}
+
+	// If out is *APIResponse, treat non-ok as error.
+	if r, ok := out.(*APIResponse); ok {
+		if strings.ToLower(r.Status) != "ok" {
+			return *r
+		}
+	}
+
+	return nil
+}
+
+func (c *Client) GetRRsets(ctx context.Context, zone string, page, pageSize int) (*GetRRsetsResponse, error) {
+	zone = strings.TrimSuffix(strings.TrimSpace(zone), ".")
+	if zone == "" {
+		return nil, fmt.Errorf("empty zone")
+	}
+	ctx = transport.WithZone(ctx, zone)
+
+	// /api/v2/zones/{zone}/rrsets  (paginated)
+	endpoint := c.baseURL.JoinPath("api", "v2", "zones", zone, "rrsets")
+	q := endpoint.Query()
+	if page > 0 {
+		q.Set("page", fmt.Sprintf("%d", page))
+	}
+	if pageSize > 0 {
+		q.Set("page_size", fmt.Sprintf("%d", pageSize))
+	}
+	endpoint.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var out GetRRsetsResponse
+	if err := c.do(req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *Client) PatchRRsets(ctx context.Context, zone string, sets []UpdateRRSet) (*APIResponse, error) {
+	zone = strings.TrimSuffix(strings.TrimSpace(zone), ".")
+	if zone == "" {
+		return nil, fmt.Errorf("empty zone")
+	}
+	ctx = transport.WithZone(ctx, zone)
+
+	// /api/v2/zones/{zone}/rrsets  PATCH
+	endpoint := c.baseURL.JoinPath("api", "v2", "zones", zone, "rrsets")
+
+	buf := new(bytes.Buffer)
+	if err := json.NewEncoder(buf).Encode(sets); err != nil {
+		return nil, fmt.Errorf("encode json: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPatch, endpoint.String(), buf)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	var out APIResponse
+	if err := c.do(req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
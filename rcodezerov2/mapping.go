@@ -0,0 +1,236 @@
+package rcodezerov2
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/libdns/libdns"
+
+	"github.com/kagescode/libdns-rcodezeroacme/internal/idn"
+	"github.com/kagescode/libdns-rcodezeroacme/internal/zonefmt"
+)
+
+// apiRecord is a libdns record in the form the API stores it: absolute
+// name, upper-case type and canonical content.
+type apiRecord struct {
+	fqdn    string
+	typ     string
+	content string
+	ttl     time.Duration
+}
+
+// toAPI maps a libdns record to the API form. Known types are formatted
+// canonically: names and target names are made absolute and ASCII
+// (relative targets are taken relative to the zone, "@" is the zone
+// itself), TXT and CAA values are quoted. Generic RRs of a known type are
+// parsed first so they get the same treatment; other types are sent as
+// given.
+func toAPI(zoneTrim string, r libdns.Record) (apiRecord, error) {
+	if rr, ok := r.(libdns.RR); ok {
+		rr.Type = strings.ToUpper(strings.TrimSpace(rr.Type))
+		parsed, err := rr.Parse()
+		if err != nil {
+			return apiRecord{}, fmt.Errorf("%s record %q: %w", rr.Type, rr.Name, err)
+		}
+		if _, generic := parsed.(libdns.RR); generic {
+			if strings.TrimSpace(rr.Type) == "" {
+				return apiRecord{}, fmt.Errorf("record %q: empty type", rr.Name)
+			}
+			return newAPIRecord(zoneTrim, rr, strings.TrimSpace(rr.Data))
+		}
+		r = parsed
+	}
+
+	rr := r.RR()
+	var (
+		content string
+		target  string
+		err     error
+	)
+	switch v := r.(type) {
+	case libdns.Address:
+		if !v.IP.IsValid() {
+			return apiRecord{}, fmt.Errorf("address record %q: invalid IP", v.Name)
+		}
+		content = v.IP.Unmap().String()
+		rr.Type = "AAAA"
+		if v.IP.Unmap().Is4() {
+			rr.Type = "A"
+		}
+	case libdns.CNAME:
+		content, err = canonicalTarget(v.Target, zoneTrim)
+	case libdns.NS:
+		content, err = canonicalTarget(v.Target, zoneTrim)
+	case libdns.MX:
+		target, err = canonicalTarget(v.Target, zoneTrim)
+		content = fmt.Sprintf("%d %s", v.Preference, target)
+	case libdns.SRV:
+		target, err = canonicalTarget(v.Target, zoneTrim)
+		content = fmt.Sprintf("%d %d %d %s", v.Priority, v.Weight, v.Port, target)
+	case libdns.CAA:
+		tag := strings.ToLower(v.Tag)
+		if !isCAATag(tag) {
+			return apiRecord{}, fmt.Errorf("CAA record %q: invalid tag %q", v.Name, v.Tag)
+		}
+		content = fmt.Sprintf("%d %s %s", v.Flags, tag, zonefmt.Quote(v.Value))
+	case libdns.ServiceBinding:
+		target = v.Target
+		if target != "." {
+			target, err = canonicalTarget(target, zoneTrim)
+		}
+		content = fmt.Sprintf("%d %s", v.Priority, target)
+		if params := v.Params.String(); v.Priority > 0 && params != "" {
+			content += " " + params
+		}
+	case libdns.TXT:
+		content = zonefmt.FormatTXT(v.Text)
+	default:
+		content = strings.TrimSpace(rr.Data)
+	}
+	if err != nil {
+		return apiRecord{}, fmt.Errorf("%s record %q: %w", rr.Type, rr.Name, err)
+	}
+	return newAPIRecord(zoneTrim, rr, content)
+}
+
+func newAPIRecord(zoneTrim string, rr libdns.RR, content string) (apiRecord, error) {
+	fqdn, err := absoluteName(rr.Name, zoneTrim)
+	if err != nil {
+		return apiRecord{}, err
+	}
+	return apiRecord{
+		fqdn:    fqdn,
+		typ:     strings.ToUpper(strings.TrimSpace(rr.Type)),
+		content: content,
+		ttl:     rr.TTL,
+	}, nil
+}
+
+// fromAPI maps one record of an rrset to the matching libdns type. Types
+// libdns has no struct for are returned as libdns.RR.
+func fromAPI(zoneTrim string, rrset RRSet, rec Record) (libdns.Record, error) {
+	rr := libdns.RR{
+		Name: libdns.RelativeName(rrset.Name, zoneTrim+"."),
+		Type: strings.ToUpper(rrset.Type),
+		TTL:  time.Duration(rrset.TTL) * time.Second,
+		Data: strings.TrimSpace(rec.Content),
+	}
+
+	switch rr.Type {
+	case "TXT":
+		text, err := zonefmt.ParseTXT(rr.Data)
+		if err != nil {
+			return nil, err
+		}
+		return libdns.TXT{Name: rr.Name, TTL: rr.TTL, Text: text}, nil
+	case "CAA":
+		return parseCAA(rr)
+	}
+	return rr.Parse()
+}
+
+// fromRecord is fromAPI for listings: content the typed mapping can't
+// parse is returned as a generic libdns.RR rather than dropped.
+func fromRecord(zoneTrim string, rrset RRSet, rec Record) libdns.Record {
+	r, err := fromAPI(zoneTrim, rrset, rec)
+	if err != nil {
+		return libdns.RR{
+			Name: libdns.RelativeName(rrset.Name, zoneTrim+"."),
+			Type: strings.ToUpper(rrset.Type),
+			TTL:  time.Duration(rrset.TTL) * time.Second,
+			Data: rec.Content,
+		}
+	}
+	return r
+}
+
+// parseCAA parses `flags tag value`, where value may be quoted and contain
+// spaces ("letsencrypt.org; validationmethods=dns-01").
+func parseCAA(rr libdns.RR) (libdns.CAA, error) {
+	fields := strings.SplitN(rr.Data, " ", 3)
+	if len(fields) != 3 {
+		return libdns.CAA{}, fmt.Errorf(`malformed CAA value %q; expected 'flags tag "value"'`, rr.Data)
+	}
+	flags, err := strconv.ParseUint(fields[0], 10, 8)
+	if err != nil {
+		return libdns.CAA{}, fmt.Errorf("invalid CAA flags %q: %v", fields[0], err)
+	}
+	value, err := zonefmt.ParseTXT(fields[2])
+	if err != nil {
+		return libdns.CAA{}, fmt.Errorf("invalid CAA value %q: %v", fields[2], err)
+	}
+	return libdns.CAA{
+		Name:  rr.Name,
+		TTL:   rr.TTL,
+		Flags: uint8(flags),
+		Tag:   strings.ToLower(fields[1]),
+		Value: value,
+	}, nil
+}
+
+// canonicalTarget returns target as an absolute ASCII name with trailing
+// dot.
+func canonicalTarget(target, zoneTrim string) (string, error) {
+	target = strings.TrimSpace(target)
+	if target == "" || target == "@" {
+		return zoneTrim + ".", nil
+	}
+	return absoluteName(target, zoneTrim)
+}
+
+// absoluteName returns name, relative to zoneTrim unless it is already
+// absolute, as an absolute ASCII name with trailing dot.
+func absoluteName(name, zoneTrim string) (string, error) {
+	return idn.ToASCII(libdns.AbsoluteName(name, zoneTrim+"."))
+}
+
+// isCAATag reports whether tag is a valid CAA property tag (RFC 8659:
+// ASCII letters and digits).
+func isCAATag(tag string) bool {
+	if tag == "" {
+		return false
+	}
+	for i := 0; i < len(tag); i++ {
+		c := tag[i]
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesAny reports whether got matches one of the deletion templates.
+// Following libdns, an empty type, zero TTL or empty data matches any value.
+// Without a type, data can't be put in canonical form and is compared as
+// given.
+func matchesAny(got apiRecord, zoneTrim string, templates []libdns.Record) bool {
+	for _, t := range templates {
+		want := t.RR()
+		fqdn, err := absoluteName(want.Name, zoneTrim)
+		if err != nil || !strings.EqualFold(fqdn, got.fqdn) {
+			continue
+		}
+		if want.Type != "" && !strings.EqualFold(want.Type, got.typ) {
+			continue
+		}
+		if want.TTL != 0 && want.TTL != got.ttl {
+			continue
+		}
+		switch {
+		case want.Data == "":
+		case strings.TrimSpace(want.Type) == "":
+			if strings.TrimSpace(want.Data) != got.content {
+				continue
+			}
+		default:
+			w, err := toAPI(zoneTrim, t)
+			if err != nil || w.content != got.content {
+				continue
+			}
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package rcodezerov2
+
+import (
+	"net/netip"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+func TestMapping_RoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		rec     libdns.Record
+		typ     string
+		content string
+	}{
+		{"A", libdns.Address{Name: "www", TTL: time.Hour, IP: netip.MustParseAddr("192.0.2.1")}, "A", "192.0.2.1"},
+		{"AAAA", libdns.Address{Name: "www", TTL: time.Hour, IP: netip.MustParseAddr("2001:db8::1")}, "AAAA", "2001:db8::1"},
+		{"CNAME", libdns.CNAME{Name: "alias", TTL: time.Hour, Target: "www.example.com."}, "CNAME", "www.example.com."},
+		{"NS", libdns.NS{Name: "sub", TTL: time.Hour, Target: "ns1.example.net."}, "NS", "ns1.example.net."},
+		{"MX", libdns.MX{Name: "@", TTL: time.Hour, Preference: 10, Target: "mx.example.com."}, "MX", "10 mx.example.com."},
+		{"SRV", libdns.SRV{Service: "sip", Transport: "tcp", Name: "@", TTL: time.Hour, Priority: 10, Weight: 5, Port: 5060, Target: "sip.example.com."}, "SRV", "10 5 5060 sip.example.com."},
+		{"CAA", libdns.CAA{Name: "@", TTL: time.Hour, Flags: 0, Tag: "issue", Value: "letsencrypt.org; validationmethods=dns-01"}, "CAA", `0 issue "letsencrypt.org; validationmethods=dns-01"`},
+		{"CAA iodef", libdns.CAA{Name: "@", TTL: time.Hour, Flags: 128, Tag: "iodef", Value: "mailto:sec@example.com"}, "CAA", `128 iodef "mailto:sec@example.com"`},
+		{"HTTPS", libdns.ServiceBinding{Scheme: "https", Name: "@", TTL: time.Hour, Priority: 1, Target: ".", Params: libdns.SvcParams{"alpn": {"h2", "h3"}}}, "HTTPS", "1 . alpn=h2,h3"},
+		{"HTTPS alias", libdns.ServiceBinding{Scheme: "https", Name: "www", TTL: time.Hour, Priority: 0, Target: "cdn.example.net.", Params: libdns.SvcParams{}}, "HTTPS", "0 cdn.example.net."},
+		{"SVCB", libdns.ServiceBinding{Scheme: "dns", Name: "resolver", TTL: time.Hour, Priority: 1, Target: "dot.example.com.", Params: libdns.SvcParams{"alpn": {"dot"}}}, "SVCB", "1 dot.example.com. alpn=dot"},
+		{"TXT", libdns.TXT{Name: "@", TTL: time.Hour, Text: `v=spf1 "x" -all`}, "TXT", `"v=spf1 \"x\" -all"`},
+		{"other", libdns.RR{Name: "@", TTL: time.Hour, Type: "SSHFP", Data: "1 1 123456789abcdef67890123456789abcdef67890"}, "SSHFP", "1 1 123456789abcdef67890123456789abcdef67890"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := toAPI("example.com", tt.rec)
+			if err != nil {
+				t.Fatalf("toAPI: %v", err)
+			}
+			if a.typ != tt.typ || a.content != tt.content {
+				t.Fatalf("toAPI = %s %q, want %s %q", a.typ, a.content, tt.typ, tt.content)
+			}
+
+			rrset := RRSet{Name: a.fqdn, Type: a.typ, TTL: int(a.ttl / time.Second)}
+			got, err := fromAPI("example.com", rrset, Record{Content: a.content})
+			if err != nil {
+				t.Fatalf("fromAPI: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.rec) {
+				t.Errorf("round trip = %#v, want %#v", got, tt.rec)
+			}
+		})
+	}
+}
+
+func TestMapping_CanonicalContent(t *testing.T) {
+	tests := []struct {
+		rec     libdns.Record
+		content string
+	}{
+		{libdns.CNAME{Name: "alias", Target: "www"}, "www.example.com."},
+		{libdns.MX{Name: "@", Preference: 5, Target: "@"}, "5 example.com."},
+		{libdns.Address{Name: "v4", IP: netip.MustParseAddr("::ffff:192.0.2.9")}, "192.0.2.9"},
+		{libdns.CAA{Name: "@", Tag: "ISSUEWILD", Value: ";"}, `0 issuewild ";"`},
+		{libdns.RR{Name: "mail", Type: "mx", Data: "10 mx1"}, "10 mx1.example.com."},
+		{libdns.RR{Name: "@", Type: "CAA", Data: `0 issue "ca.example"`}, `0 issue "ca.example"`},
+	}
+	for _, tt := range tests {
+		a, err := toAPI("example.com", tt.rec)
+		if err != nil {
+			t.Errorf("toAPI(%#v): %v", tt.rec, err)
+			continue
+		}
+		if a.content != tt.content {
+			t.Errorf("toAPI(%#v) content = %q, want %q", tt.rec, a.content, tt.content)
+		}
+	}
+}
+
+func TestMapping_Errors(t *testing.T) {
+	for _, rec := range []libdns.Record{
+		libdns.Address{Name: "www"},
+		libdns.CAA{Name: "@", Tag: "is-sue", Value: "x"},
+		libdns.RR{Name: "www", Type: "MX", Data: "ten mx"},
+		libdns.RR{Name: "www", Data: "x"},
+	} {
+		if _, err := toAPI("example.com", rec); err == nil {
+			t.Errorf("toAPI(%#v) succeeded", rec)
+		}
+	}
+
+	got := fromRecord("example.com", RRSet{Name: "x.example.com.", Type: "MX", TTL: 60}, Record{Content: "bogus"})
+	if rr, ok := got.(libdns.RR); !ok || rr.Data != "bogus" || rr.Name != "x" {
+		t.Errorf("fromRecord on bad content = %#v", got)
+	}
+}
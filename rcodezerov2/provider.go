@@ -0,0 +1,369 @@
+// Package rcodezerov2 implements a libdns provider for full DNS record
+// management through the RcodeZero v2 API. Use the parent package for
+// ACME-only tokens.
+package rcodezerov2
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/libdns/libdns"
+
+	rcodezeroacme "github.com/kagescode/libdns-rcodezeroacme"
+	"github.com/kagescode/libdns-rcodezeroacme/internal/idn"
+	"github.com/kagescode/libdns-rcodezeroacme/internal/transport"
+)
+
+// defaultTTL is used for new rrsets whose records don't specify a TTL.
+const defaultTTL = 3600
+
+type Provider struct {
+	APIToken string
+	BaseURL  string
+
+	// TokenSource, if set, is consulted for the bearer token on every
+	// request and takes precedence over APIToken.
+	TokenSource rcodezeroacme.TokenSource
+
+	HTTPClient rcodezeroacme.HTTPClient
+
+	// Retries is how often a failed read is retried (network errors, 429,
+	// 502/503/504). Writes are never retried.
+	Retries int
+
+	// MaxResponseSize limits API response bodies in bytes; 4 MiB if zero.
+	MaxResponseSize int64
+
+	// UserAgentSuffix is appended to the User-Agent to identify the
+	// caller, e.g. "caddy/2.8".
+	UserAgentSuffix string
+
+	// Headers are sent with every API request, e.g. for tracing.
+	Headers map[string]string
+
+	client *Client
+}
+
+var (
+	_ libdns.RecordGetter   = (*Provider)(nil)
+	_ libdns.RecordAppender = (*Provider)(nil)
+	_ libdns.RecordSetter   = (*Provider)(nil)
+	_ libdns.RecordDeleter  = (*Provider)(nil)
+)
+
+func (p *Provider) init() error {
+	if p.client != nil {
+		return nil
+	}
+	ts := p.TokenSource
+	if ts == nil {
+		ts = rcodezeroacme.StaticToken(p.APIToken)
+	}
+	c, err := NewClientWithTokenSource(ts, p.BaseURL, p.HTTPClient)
+	if err != nil {
+		return err
+	}
+	c.Retries = p.Retries
+	c.MaxResponseSize = p.MaxResponseSize
+	c.UserAgentSuffix = p.UserAgentSuffix
+	c.Header = transport.HeaderFromMap(p.Headers)
+	p.client = c
+	return nil
+}
+
+func (p *Provider) GetRecords(ctx context.Context, zone string) ([]libdns.Record, error) {
+	zoneTrim, rrsets, err := p.rrsets(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []libdns.Record
+	for _, rrset := range rrsets {
+		for _, rec := range rrset.Records {
+			if rec.Disabled {
+				continue
+			}
+			out = append(out, fromRecord(zoneTrim, rrset, rec))
+		}
+	}
+	return out, nil
+}
+
+// AppendRecords adds the records to their rrsets, keeping existing values.
+// The returned records are the input records as stored: absolute names
+// made relative to the zone, canonical content and the rrset's TTL.
+func (p *Provider) AppendRecords(ctx context.Context, zone string, recs []libdns.Record) ([]libdns.Record, error) {
+	zoneTrim, rrsets, err := p.rrsets(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+	existing := indexRRsets(zoneTrim, rrsets)
+	groups, err := groupRecords(zoneTrim, recs)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		sets []UpdateRRSet
+		out  []libdns.Record
+	)
+	for _, g := range groups {
+		cur, ok := existing[g.key]
+		if !ok {
+			sets = append(sets, UpdateRRSet{
+				Name:       g.fqdn,
+				Type:       g.typ,
+				TTL:        g.ttl(),
+				ChangeType: changeTypeAdd,
+				Records:    g.records(),
+			})
+			out = append(out, g.stored(zoneTrim, g.ttl())...)
+			continue
+		}
+
+		merged := append([]Record(nil), cur.Records...)
+		have := map[string]bool{}
+		for _, rec := range cur.Records {
+			have[canonicalContent(zoneTrim, cur, rec)] = true
+		}
+		for _, r := range g.records() {
+			if !have[r.Content] {
+				have[r.Content] = true
+				merged = append(merged, r)
+			}
+		}
+		sets = append(sets, UpdateRRSet{
+			Name:       g.fqdn,
+			Type:       g.typ,
+			TTL:        cur.TTL,
+			ChangeType: changeTypeUpdate,
+			Records:    merged,
+		})
+		out = append(out, g.stored(zoneTrim, cur.TTL)...)
+	}
+
+	if err := p.patch(ctx, zoneTrim, sets); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SetRecords replaces each (name, type) rrset named in recs with exactly
+// the given records. Other rrsets are left alone. The returned records are
+// as stored, see AppendRecords.
+func (p *Provider) SetRecords(ctx context.Context, zone string, recs []libdns.Record) ([]libdns.Record, error) {
+	zoneTrim, rrsets, err := p.rrsets(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+	existing := indexRRsets(zoneTrim, rrsets)
+	groups, err := groupRecords(zoneTrim, recs)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		sets []UpdateRRSet
+		out  []libdns.Record
+	)
+	for _, g := range groups {
+		change := changeTypeUpdate
+		if _, ok := existing[g.key]; !ok {
+			change = changeTypeAdd
+		}
+		sets = append(sets, UpdateRRSet{
+			Name:       g.fqdn,
+			Type:       g.typ,
+			TTL:        g.ttl(),
+			ChangeType: change,
+			Records:    g.records(),
+		})
+		out = append(out, g.stored(zoneTrim, g.ttl())...)
+	}
+
+	if err := p.patch(ctx, zoneTrim, sets); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DeleteRecords removes matching records. Following libdns, an empty type,
+// zero TTL or empty data in an input record matches any value. Rrsets left
+// without records are deleted. The records actually removed are returned.
+func (p *Provider) DeleteRecords(ctx context.Context, zone string, recs []libdns.Record) ([]libdns.Record, error) {
+	zoneTrim, rrsets, err := p.rrsets(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		sets    []UpdateRRSet
+		deleted []libdns.Record
+	)
+	for _, rrset := range rrsets {
+		var keep []Record
+		removed := false
+		for _, rec := range rrset.Records {
+			got := apiRecord{
+				fqdn:    libdns.AbsoluteName(rrset.Name, zoneTrim+"."),
+				typ:     strings.ToUpper(rrset.Type),
+				content: canonicalContent(zoneTrim, rrset, rec),
+				ttl:     time.Duration(rrset.TTL) * time.Second,
+			}
+			if matchesAny(got, zoneTrim, recs) {
+				deleted = append(deleted, fromRecord(zoneTrim, rrset, rec))
+				removed = true
+				continue
+			}
+			keep = append(keep, rec)
+		}
+		if !removed {
+			continue
+		}
+
+		set := UpdateRRSet{Name: rrset.Name, Type: rrset.Type, TTL: rrset.TTL}
+		if len(keep) == 0 {
+			set.ChangeType = changeTypeDelete
+		} else {
+			set.ChangeType = changeTypeUpdate
+			set.Records = keep
+		}
+		sets = append(sets, set)
+	}
+
+	if err := p.patch(ctx, zoneTrim, sets); err != nil {
+		return nil, err
+	}
+	return deleted, nil
+}
+
+// rrsets returns the normalized zone and all its rrsets.
+func (p *Provider) rrsets(ctx context.Context, zone string) (string, []RRSet, error) {
+	if err := p.init(); err != nil {
+		return "", nil, err
+	}
+	zoneTrim, err := idn.TrimZone(zone)
+	if err != nil {
+		return "", nil, err
+	}
+	if zoneTrim == "" {
+		return "", nil, fmt.Errorf("empty zone")
+	}
+
+	var out []RRSet
+	page, pageSize := 1, 100
+	for {
+		resp, err := p.client.GetRRsets(ctx, zoneTrim, page, pageSize)
+		if err != nil {
+			return "", nil, err
+		}
+		out = append(out, resp.Data...)
+
+		if resp.LastPage <= page || resp.LastPage == 0 {
+			break
+		}
+		page++
+	}
+	return zoneTrim, out, nil
+}
+
+func (p *Provider) patch(ctx context.Context, zoneTrim string, sets []UpdateRRSet) error {
+	if len(sets) == 0 {
+		return nil
+	}
+	_, err := p.client.PatchRRsets(ctx, zoneTrim, sets)
+	return err
+}
+
+// recordGroup collects the input records of one (name, type) rrset.
+type recordGroup struct {
+	key  string
+	fqdn string
+	typ  string
+	recs []apiRecord
+}
+
+func (g recordGroup) ttl() int {
+	for _, r := range g.recs {
+		if r.ttl > 0 {
+			return int((r.ttl + time.Second - 1) / time.Second)
+		}
+	}
+	return defaultTTL
+}
+
+func (g recordGroup) records() []Record {
+	out := make([]Record, 0, len(g.recs))
+	seen := map[string]bool{}
+	for _, r := range g.recs {
+		if !seen[r.content] {
+			seen[r.content] = true
+			out = append(out, Record{Content: r.content})
+		}
+	}
+	return out
+}
+
+// stored returns the group's records as written to an rrset with ttl
+// seconds.
+func (g recordGroup) stored(zoneTrim string, ttl int) []libdns.Record {
+	rrset := RRSet{Name: g.fqdn, Type: g.typ, TTL: ttl}
+	recs := g.records()
+	out := make([]libdns.Record, 0, len(recs))
+	for _, r := range recs {
+		out = append(out, fromRecord(zoneTrim, rrset, r))
+	}
+	return out
+}
+
+// groupRecords maps recs to the API form and groups them by (name, type)
+// in order of first appearance.
+func groupRecords(zoneTrim string, recs []libdns.Record) ([]recordGroup, error) {
+	var groups []recordGroup
+	index := map[string]int{}
+	for _, r := range recs {
+		a, err := toAPI(zoneTrim, r)
+		if err != nil {
+			return nil, err
+		}
+		key := rrsetKey(a.fqdn, a.typ)
+
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, recordGroup{key: key, fqdn: a.fqdn, typ: a.typ})
+		}
+		groups[i].recs = append(groups[i].recs, a)
+	}
+	return groups, nil
+}
+
+func indexRRsets(zoneTrim string, rrsets []RRSet) map[string]RRSet {
+	out := make(map[string]RRSet, len(rrsets))
+	for _, rr := range rrsets {
+		out[rrsetKey(libdns.AbsoluteName(rr.Name, zoneTrim+"."), rr.Type)] = rr
+	}
+	return out
+}
+
+func rrsetKey(fqdn, typ string) string {
+	return strings.ToLower(strings.TrimSuffix(fqdn, ".")) + "|" + strings.ToUpper(typ)
+}
+
+// canonicalContent returns the content of a stored record as toAPI would
+// format it, so stored and requested values compare equal regardless of
+// how the API spelled them. Unparseable content is returned as stored.
+func canonicalContent(zoneTrim string, rrset RRSet, rec Record) string {
+	r, err := fromAPI(zoneTrim, rrset, rec)
+	if err != nil {
+		return rec.Content
+	}
+	a, err := toAPI(zoneTrim, r)
+	if err != nil {
+		return rec.Content
+	}
+	return a.content
+}
@@ -0,0 +1,286 @@
+package rcodezerov2
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/netip"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+
+	"github.com/kagescode/libdns-rcodezeroacme/internal/apitest"
+)
+
+// fakeV2 is an in-memory stand-in for the v2 rrsets endpoint.
+type fakeV2 struct {
+	mu      sync.Mutex
+	rrsets  map[string]*RRSet // by rrsetKey
+	patches [][]UpdateRRSet
+}
+
+func newFakeV2() *fakeV2 { return &fakeV2{rrsets: map[string]*RRSet{}} }
+
+func (f *fakeV2) Do(req *http.Request) (*http.Response, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !strings.HasPrefix(req.URL.Path, "/api/v2/zones/") {
+		return apitest.JSONResponse(404, `{"status":"failed","message":"not found"}`), nil
+	}
+
+	if req.Method == http.MethodGet {
+		out := GetRRsetsResponse{CurrentPage: 1, LastPage: 1}
+		keys := make([]string, 0, len(f.rrsets))
+		for k := range f.rrsets {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			out.Data = append(out.Data, *f.rrsets[k])
+		}
+		raw, _ := json.Marshal(out)
+		return apitest.JSONResponse(200, string(raw)), nil
+	}
+
+	var sets []UpdateRRSet
+	if err := json.NewDecoder(req.Body).Decode(&sets); err != nil {
+		return apitest.JSONResponse(400, `{"status":"failed","message":"bad json"}`), nil
+	}
+	f.patches = append(f.patches, sets)
+
+	for _, s := range sets {
+		key := rrsetKey(s.Name, s.Type)
+		switch s.ChangeType {
+		case changeTypeAdd, changeTypeUpdate:
+			f.rrsets[key] = &RRSet{Name: s.Name, Type: s.Type, TTL: s.TTL, Records: s.Records}
+		case changeTypeDelete:
+			delete(f.rrsets, key)
+		}
+	}
+	return apitest.JSONResponse(200, `{"status":"ok","message":""}`), nil
+}
+
+func (f *fakeV2) contents(name, typ string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	rr := f.rrsets[rrsetKey(name, typ)]
+	if rr == nil {
+		return nil
+	}
+	var out []string
+	for _, r := range rr.Records {
+		out = append(out, r.Content)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func TestProvider_AppendSetDelete(t *testing.T) {
+	api := newFakeV2()
+	p := &Provider{APIToken: apitest.Token, HTTPClient: api}
+	ctx := context.Background()
+
+	_, err := p.AppendRecords(ctx, "example.com.", []libdns.Record{
+		libdns.RR{Name: "www", Type: "A", Data: "192.0.2.1"},
+		libdns.RR{Name: "www", Type: "A", Data: "192.0.2.2"},
+		libdns.TXT{Name: "@", Text: `v=spf1 "-all"`, TTL: time.Hour},
+	})
+	if err != nil {
+		t.Fatalf("AppendRecords: %v", err)
+	}
+	if len(api.patches) != 1 || len(api.patches[0]) != 2 {
+		t.Fatalf("expected one PATCH with two rrsets, got %+v", api.patches)
+	}
+	if got := api.contents("www.example.com.", "A"); strings.Join(got, ",") != "192.0.2.1,192.0.2.2" {
+		t.Fatalf("A contents = %q", got)
+	}
+	if got := api.contents("example.com.", "TXT"); len(got) != 1 || got[0] != `"v=spf1 \"-all\""` {
+		t.Fatalf("TXT contents = %q", got)
+	}
+
+	// Appending to an existing rrset keeps its values and TTL.
+	if _, err := p.AppendRecords(ctx, "example.com.", []libdns.Record{
+		libdns.RR{Name: "www", Type: "A", Data: "192.0.2.3", TTL: time.Minute},
+	}); err != nil {
+		t.Fatalf("AppendRecords: %v", err)
+	}
+	set := api.patches[1][0]
+	if set.ChangeType != changeTypeUpdate || set.TTL != defaultTTL || len(set.Records) != 3 {
+		t.Fatalf("append to existing rrset sent %+v", set)
+	}
+
+	if _, err := p.SetRecords(ctx, "example.com.", []libdns.Record{
+		libdns.RR{Name: "www", Type: "A", Data: "198.51.100.7", TTL: 5 * time.Minute},
+	}); err != nil {
+		t.Fatalf("SetRecords: %v", err)
+	}
+	if got := api.contents("www.example.com.", "A"); len(got) != 1 || got[0] != "198.51.100.7" {
+		t.Fatalf("A contents after set = %q", got)
+	}
+	if set := api.patches[2][0]; set.ChangeType != changeTypeUpdate {
+		t.Fatalf("set of existing rrset sent %q, want %q", set.ChangeType, changeTypeUpdate)
+	}
+
+	// Setting an rrset that does not exist yet creates it.
+	if _, err := p.SetRecords(ctx, "example.com.", []libdns.Record{
+		libdns.RR{Name: "mail", Type: "AAAA", Data: "2001:db8::25"},
+	}); err != nil {
+		t.Fatalf("SetRecords: %v", err)
+	}
+	if set := api.patches[3][0]; set.ChangeType != changeTypeAdd || set.Name != "mail.example.com." {
+		t.Fatalf("set of new rrset sent %+v, want changetype %q", set, changeTypeAdd)
+	}
+
+	recs, err := p.GetRecords(ctx, "example.com.")
+	if err != nil {
+		t.Fatalf("GetRecords: %v", err)
+	}
+	var txt *libdns.TXT
+	for _, r := range recs {
+		if v, ok := r.(libdns.TXT); ok {
+			txt = &v
+		}
+	}
+	if txt == nil || txt.Name != "@" || txt.Text != `v=spf1 "-all"` || txt.TTL != time.Hour {
+		t.Fatalf("GetRecords TXT = %+v (all: %v)", txt, recs)
+	}
+
+	deleted, err := p.DeleteRecords(ctx, "example.com.", []libdns.Record{libdns.RR{Name: "www"}})
+	if err != nil {
+		t.Fatalf("DeleteRecords: %v", err)
+	}
+	if len(deleted) != 1 || deleted[0].RR().Data != "198.51.100.7" {
+		t.Fatalf("deleted = %v", deleted)
+	}
+	if got := api.contents("www.example.com.", "A"); got != nil {
+		t.Errorf("A rrset left: %q", got)
+	}
+}
+
+func TestProvider_DeleteKeepsOtherValues(t *testing.T) {
+	api := newFakeV2()
+	p := &Provider{APIToken: apitest.Token, HTTPClient: api}
+	ctx := context.Background()
+
+	mx := []libdns.Record{
+		libdns.MX{Name: "@", Preference: 10, Target: "mx1.example.com."},
+		libdns.MX{Name: "@", Preference: 20, Target: "mx2.example.com."},
+	}
+	if _, err := p.AppendRecords(ctx, "example.com", mx); err != nil {
+		t.Fatalf("AppendRecords: %v", err)
+	}
+
+	deleted, err := p.DeleteRecords(ctx, "example.com", mx[:1])
+	if err != nil || len(deleted) != 1 {
+		t.Fatalf("DeleteRecords = %v, %v", deleted, err)
+	}
+	if _, ok := deleted[0].(libdns.MX); !ok {
+		t.Errorf("deleted record is %T, want libdns.MX", deleted[0])
+	}
+	last := api.patches[len(api.patches)-1][0]
+	if last.ChangeType != changeTypeUpdate {
+		t.Errorf("changetype = %q, want update", last.ChangeType)
+	}
+	if got := api.contents("example.com.", "MX"); len(got) != 1 || got[0] != "20 mx2.example.com." {
+		t.Errorf("MX contents = %q", got)
+	}
+}
+
+func TestProvider_ReturnsRecordsAsStored(t *testing.T) {
+	api := newFakeV2()
+	p := &Provider{APIToken: apitest.Token, HTTPClient: api}
+	ctx := context.Background()
+
+	if _, err := p.AppendRecords(ctx, "example.com.", []libdns.Record{
+		libdns.RR{Name: "www", Type: "A", Data: "192.0.2.1", TTL: time.Hour},
+	}); err != nil {
+		t.Fatalf("AppendRecords: %v", err)
+	}
+
+	// The rrset keeps its TTL; the CNAME target is made absolute.
+	got, err := p.AppendRecords(ctx, "example.com.", []libdns.Record{
+		libdns.RR{Name: "www.example.com.", Type: "a", Data: "192.0.2.2", TTL: time.Minute},
+		libdns.CNAME{Name: "alias", Target: "www"},
+	})
+	if err != nil {
+		t.Fatalf("AppendRecords: %v", err)
+	}
+	want := []libdns.Record{
+		libdns.Address{Name: "www", TTL: time.Hour, IP: netip.MustParseAddr("192.0.2.2")},
+		libdns.CNAME{Name: "alias", TTL: defaultTTL * time.Second, Target: "www.example.com."},
+	}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("AppendRecords = %+v, want %+v", got, want)
+	}
+
+	got, err = p.SetRecords(ctx, "example.com.", []libdns.Record{libdns.MX{Name: "@", Preference: 10, Target: "mx"}})
+	if err != nil || len(got) != 1 || got[0] != (libdns.MX{Name: "@", TTL: defaultTTL * time.Second, Preference: 10, Target: "mx.example.com."}) {
+		t.Fatalf("SetRecords = %+v, %v", got, err)
+	}
+}
+
+func TestProvider_DeleteUntypedTemplateMatchesData(t *testing.T) {
+	api := newFakeV2()
+	p := &Provider{APIToken: apitest.Token, HTTPClient: api}
+	ctx := context.Background()
+
+	if _, err := p.AppendRecords(ctx, "example.com.", []libdns.Record{
+		libdns.RR{Name: "www", Type: "A", Data: "192.0.2.1"},
+		libdns.RR{Name: "www", Type: "A", Data: "192.0.2.2"},
+	}); err != nil {
+		t.Fatalf("AppendRecords: %v", err)
+	}
+
+	deleted, err := p.DeleteRecords(ctx, "example.com.", []libdns.Record{libdns.RR{Name: "www", Data: "192.0.2.1"}})
+	if err != nil || len(deleted) != 1 || deleted[0].RR().Data != "192.0.2.1" {
+		t.Fatalf("DeleteRecords = %v, %v", deleted, err)
+	}
+	if got := api.contents("www.example.com.", "A"); len(got) != 1 || got[0] != "192.0.2.2" {
+		t.Errorf("A contents = %q", got)
+	}
+}
+
+func TestProvider_InternationalizedNames(t *testing.T) {
+	api := newFakeV2()
+	p := &Provider{APIToken: apitest.Token, HTTPClient: api}
+	ctx := context.Background()
+
+	got, err := p.AppendRecords(ctx, "bücher.example.", []libdns.Record{
+		libdns.CNAME{Name: "läden", Target: "shop.bücher.example."},
+	})
+	if err != nil {
+		t.Fatalf("AppendRecords: %v", err)
+	}
+	if c := api.contents("xn--lden-loa.xn--bcher-kva.example.", "CNAME"); len(c) != 1 || c[0] != "shop.xn--bcher-kva.example." {
+		t.Fatalf("CNAME contents = %q (rrsets %v)", c, api.rrsets)
+	}
+	if len(got) != 1 || got[0].RR().Name != "xn--lden-loa" {
+		t.Fatalf("AppendRecords = %+v", got)
+	}
+
+	deleted, err := p.DeleteRecords(ctx, "bücher.example", []libdns.Record{libdns.RR{Name: "läden", Type: "CNAME"}})
+	if err != nil || len(deleted) != 1 {
+		t.Fatalf("DeleteRecords = %v, %v", deleted, err)
+	}
+}
+
+func TestProvider_ErrorsNameV2API(t *testing.T) {
+	hc := &failingV2{status: 503}
+	p := &Provider{APIToken: apitest.Token, HTTPClient: hc}
+	_, err := p.GetRecords(context.Background(), "example.com.")
+	if err == nil || !strings.HasPrefix(err.Error(), "rcodezero v2 http 503: ") {
+		t.Fatalf("err = %v, want it attributed to the v2 API", err)
+	}
+}
+
+// failingV2 answers every request with status.
+type failingV2 struct{ status int }
+
+func (f *failingV2) Do(*http.Request) (*http.Response, error) {
+	return apitest.JSONResponse(f.status, `{"status":"failed","message":"unavailable"}`), nil
+}
@@ -0,0 +1,55 @@
+package rcodezerov2
+
+import (
+	"log/slog"
+
+	"github.com/kagescode/libdns-rcodezeroacme/internal/transport"
+)
+
+// String, GoString and LogValue keep API tokens out of fmt output and
+// structured logs. As in the root package, only *Provider and *Client are
+// redacted; a Provider value prints its fields as is.
+
+func (p *Provider) String() string { return p.describe() }
+
+func (p *Provider) GoString() string { return "&" + p.describe() }
+
+func (p *Provider) LogValue() slog.Value {
+	if p == nil {
+		return slog.StringValue("<nil>")
+	}
+	return slog.GroupValue(p.info().Attrs()...)
+}
+
+func (p *Provider) describe() string {
+	if p == nil {
+		return "<nil>"
+	}
+	return "rcodezerov2.Provider{" + p.info().Fields() + "}"
+}
+
+func (p *Provider) info() transport.ProviderInfo {
+	return transport.ProviderInfo{BaseURL: p.BaseURL, APIToken: p.APIToken, TokenSource: p.TokenSource, Retries: p.Retries}
+}
+
+func (c *Client) String() string { return c.describe() }
+
+func (c *Client) GoString() string { return "&" + c.describe() }
+
+func (c *Client) LogValue() slog.Value {
+	if c == nil {
+		return slog.StringValue("<nil>")
+	}
+	return c.info().LogValue()
+}
+
+func (c *Client) describe() string {
+	if c == nil {
+		return "<nil>"
+	}
+	return c.info().String()
+}
+
+func (c *Client) info() transport.ClientInfo {
+	return transport.ClientInfo{Type: "rcodezerov2.Client", BaseURL: c.baseURL.String(), Tokens: c.tokens, Retries: c.Retries}
+}
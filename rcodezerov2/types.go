@@ -0,0 +1,18 @@
+package rcodezerov2
+
+import rcodezeroacme "github.com/kagescode/libdns-rcodezeroacme"
+
+// The v2 rrset endpoints use the same payloads as the ACME endpoint.
+type (
+	UpdateRRSet       = rcodezeroacme.UpdateRRSet
+	Record            = rcodezeroacme.Record
+	RRSet             = rcodezeroacme.RRSet
+	APIResponse       = rcodezeroacme.APIResponse
+	GetRRsetsResponse = rcodezeroacme.GetRRsetsResponse
+)
+
+const (
+	changeTypeAdd    = "add"
+	changeTypeUpdate = "update"
+	changeTypeDelete = "delete"
+)
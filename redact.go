@@ -0,0 +1,111 @@
+package rcodezeroacme
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"github.com/kagescode/libdns-rcodezeroacme/internal/transport"
+)
+
+// String, GoString and LogValue keep API tokens out of fmt output
+// (including %v, %+v and %#v) and structured logs. Provider and Client
+// implement them on the pointer only, as a Provider holds locks that a
+// value receiver would copy: print or log a *Provider, never a Provider
+// value, whose fields fmt and slog show as is.
+
+func (s StaticToken) String() string { return transport.RedactSecret(string(s)) }
+func (s StaticToken) GoString() string {
+	return fmt.Sprintf("rcodezeroacme.StaticToken(%q)", s.String())
+}
+func (s StaticToken) LogValue() slog.Value {
+	return slog.StringValue(s.String())
+}
+
+func (f *FileToken) String() string   { return fmt.Sprintf("FileToken{Path:%q}", f.Path) }
+func (f *FileToken) GoString() string { return "&rcodezeroacme." + f.String() }
+func (f *FileToken) LogValue() slog.Value {
+	return slog.GroupValue(slog.String("path", f.Path))
+}
+
+func (c *CommandToken) String() string {
+	return fmt.Sprintf("CommandToken{Command:%q, Args:%q, TTL:%s}", c.Command, c.Args, c.TTL)
+}
+func (c *CommandToken) GoString() string { return "&rcodezeroacme." + c.String() }
+func (c *CommandToken) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("command", c.Command),
+		slog.Any("args", c.Args),
+		slog.Duration("ttl", c.TTL),
+	)
+}
+
+func (p *Provider) String() string { return p.describe() }
+
+func (p *Provider) GoString() string { return "&" + p.describe() }
+
+func (p *Provider) LogValue() slog.Value {
+	if p == nil {
+		return slog.StringValue("<nil>")
+	}
+	return slog.GroupValue(append(p.info().Attrs(),
+		slog.Any("zones", p.Zones),
+		slog.Any("zone_tokens", redactedZones(p.ZoneTokens)),
+		slog.String("creator_id", p.CreatorID),
+	)...)
+}
+
+func (p *Provider) describe() string {
+	if p == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("rcodezeroacme.Provider{%s, Zones:%q, ZoneTokens:%s, CreatorID:%q, OwnedOnly:%t, TrackSerial:%t}",
+		p.info().Fields(), p.Zones, formatZoneTokens(p.ZoneTokens), p.CreatorID, p.OwnedOnly, p.TrackSerial)
+}
+
+func (p *Provider) info() transport.ProviderInfo {
+	return transport.ProviderInfo{BaseURL: p.BaseURL, APIToken: p.APIToken, TokenSource: p.TokenSource, Retries: p.Retries}
+}
+
+func (c *Client) String() string { return c.describe() }
+
+func (c *Client) GoString() string { return "&" + c.describe() }
+
+func (c *Client) LogValue() slog.Value {
+	if c == nil {
+		return slog.StringValue("<nil>")
+	}
+	return c.info().LogValue()
+}
+
+func (c *Client) describe() string {
+	if c == nil {
+		return "<nil>"
+	}
+	return c.info().String()
+}
+
+func (c *Client) info() transport.ClientInfo {
+	return transport.ClientInfo{Type: "rcodezeroacme.Client", BaseURL: c.baseURL.String(), Tokens: c.tokens, Retries: c.Retries}
+}
+
+func redactedZones(tokens map[string]string) map[string]string {
+	if len(tokens) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(tokens))
+	for zone, tok := range tokens {
+		out[zone] = transport.RedactSecret(tok)
+	}
+	return out
+}
+
+func formatZoneTokens(tokens map[string]string) string {
+	zones := make([]string, 0, len(tokens))
+	for zone, tok := range tokens {
+		zones = append(zones, fmt.Sprintf("%q:%q", zone, transport.RedactSecret(tok)))
+	}
+	sort.Strings(zones)
+	return "map[" + strings.Join(zones, ", ") + "]"
+}
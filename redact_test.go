@@ -0,0 +1,92 @@
+package rcodezeroacme
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/kagescode/libdns-rcodezeroacme/internal/apitest"
+)
+
+const secret = "s3cr3t-token-value"
+
+func TestProvider_RedactsTokens(t *testing.T) {
+	p := &Provider{
+		APIToken:    secret,
+		TokenSource: StaticToken(secret),
+		ZoneTokens:  map[string]string{"example.com": secret},
+		Zones:       []string{"example.com"},
+	}
+	if err := p.init(); err != nil {
+		t.Fatal(err)
+	}
+
+	var logs bytes.Buffer
+	slog.New(slog.NewTextHandler(&logs, nil)).Info("provider", "p", p, "c", p.client, "ts", StaticToken(secret))
+
+	for _, out := range []string{
+		fmt.Sprint(p), fmt.Sprintf("%+v", p), fmt.Sprintf("%#v", p),
+		fmt.Sprint(StaticToken(secret)), fmt.Sprintf("%#v", StaticToken(secret)),
+		logs.String(),
+	} {
+		if strings.Contains(out, secret) {
+			t.Errorf("token leaked: %s", out)
+		}
+		if !strings.Contains(out, "REDACTED") {
+			t.Errorf("no redaction marker: %s", out)
+		}
+	}
+	for _, out := range []string{fmt.Sprint(p.client), fmt.Sprintf("%#v", p.client)} {
+		if strings.Contains(out, secret) {
+			t.Errorf("token leaked: %s", out)
+		}
+	}
+	if !strings.Contains(fmt.Sprint(p), `"example.com"`) {
+		t.Errorf("String lost non-secret fields: %s", p)
+	}
+
+	logs.Reset()
+	slog.New(slog.NewJSONHandler(&logs, nil)).Info("sources",
+		"file", &FileToken{Path: "/run/secrets/token"},
+		"cmd", &CommandToken{Command: "vault", Args: []string{"read", "token"}})
+	if out := logs.String(); !strings.Contains(out, `"file":{"path":"/run/secrets/token"}`) || !strings.Contains(out, `"command":"vault"`) {
+		t.Errorf("token sources not logged as groups: %s", out)
+	}
+}
+
+func TestHTTPError_SanitizedBody(t *testing.T) {
+	page := "<!DOCTYPE html><html><head><style>body{}</style></head><body><h1>Maintenance</h1>\n" +
+		"<p>Authorization: Bearer " + secret + "</p>" + strings.Repeat("<p>filler</p>", 500) + "</body></html>"
+	hc := fakeHTTP(func(*http.Request) (*http.Response, error) {
+		resp := apitest.JSONResponse(500, page)
+		return resp, nil
+	})
+	c, err := NewClient(secret, "", hc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = c.GetRRsets(context.Background(), "example.com", 1, 1)
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) {
+		t.Fatalf("err = %v", err)
+	}
+	msg := err.Error()
+	if strings.Contains(msg, secret) || strings.Contains(msg, "<") {
+		t.Errorf("unsanitized error: %s", msg)
+	}
+	if !strings.HasPrefix(msg, "rcodezero acme http 500: ") {
+		t.Errorf("error not attributed to the ACME API: %s", msg)
+	}
+	if !strings.HasPrefix(httpErr.Body, "Maintenance Authorization: Bearer [REDACTED]") {
+		t.Errorf("body = %q", httpErr.Body)
+	}
+	if len(httpErr.Body) > 600 || !strings.Contains(httpErr.Body, "bytes total") {
+		t.Errorf("body not truncated: %d bytes", len(httpErr.Body))
+	}
+}
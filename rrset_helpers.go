@@ -3,6 +3,8 @@ package rcodezeroacme
 import (
 	"context"
 	"strings"
+
+	"github.com/libdns/libdns"
 )
 
 // normalizeName normalizes rrset names for comparisons.
@@ -10,13 +12,27 @@ func normalizeName(n string) string {
 	return strings.ToLower(strings.TrimSuffix(strings.TrimSpace(n), "."))
 }
 
-// getExistingTXTValues returns (valueSet, ttl, error) for a TXT rrset name.
-// rrsetFQDN can be "_acme-challenge.example.com." or "_acme-challenge".
-func (p *Provider) getExistingTXTValues(ctx context.Context, zoneTrim string, rrsetFQDN string) (map[string]bool, int, error) {
-	want := normalizeName(rrsetFQDN)
+// absoluteInZone returns the normalized absolute form of name, treating
+// names that don't end in the zone as relative to it.
+func absoluteInZone(name, zoneTrim string) string {
+	n, z := normalizeName(name), normalizeName(zoneTrim)
+	if n == z || strings.HasSuffix(n, "."+z) {
+		return n
+	}
+	if n == "" || n == "@" {
+		return z
+	}
+	return n + "." + z
+}
+
+// getExistingTXTValues returns (values, ttl, error) for a TXT rrset name.
+// values maps each decoded TXT value to the content string the API stored
+// for it. rrsetFQDN can be "_acme-challenge.example.com." or "_acme-challenge".
+func (p *Provider) getExistingTXTValues(ctx context.Context, zoneTrim string, rrsetFQDN string) (map[string]string, int, error) {
+	want := absoluteInZone(rrsetFQDN, zoneTrim)
 
 	page, pageSize := 1, 100
-	values := map[string]bool{}
+	values := map[string]string{}
 	ttl := 0
 
 	for {
@@ -30,14 +46,10 @@ func (p *Provider) getExistingTXTValues(ctx context.Context, zoneTrim string, rr
 				continue
 			}
 
-			// Accept both "_acme-challenge" and "_acme-challenge.<zone>."
-			n := normalizeName(rr.Name)
-			if n != want {
-				// If caller passed "_acme-challenge.<zone>", but API returns "_acme-challenge" (or vice versa),
-				// treat them as equivalent if both are ACME challenge names.
-				if !(isAcmeChallengeName(rr.Name) && isAcmeChallengeName(rrsetFQDN)) {
-					continue
-				}
+			// Accept both "_acme-challenge" and "_acme-challenge.<zone>.", but
+			// never confuse different labels (servera vs serverb).
+			if absoluteInZone(rr.Name, zoneTrim) != want {
+				continue
 			}
 
 			ttl = rr.TTL
@@ -45,7 +57,7 @@ func (p *Provider) getExistingTXTValues(ctx context.Context, zoneTrim string, rr
 				if r.Disabled {
 					continue
 				}
-				values[unquoteTXT(r.Content)] = true
+				values[decodeTXT(r.Content)] = r.Content
 			}
 			return values, ttl, nil
 		}
@@ -59,3 +71,75 @@ func (p *Provider) getExistingTXTValues(ctx context.Context, zoneTrim string, rr
 	return values, 0, nil // not found
 }
 
+// listAcmeTXT returns all challenge TXT rrsets of the zone: _acme-challenge
+// names and, if the name policy allows them, DNS-ACCOUNT-01 names.
+func (p *Provider) listAcmeTXT(ctx context.Context, zoneTrim string) ([]RRSet, error) {
+	page, pageSize := 1, 100
+	var out []RRSet
+
+	for {
+		resp, err := p.client.GetRRsets(ctx, zoneTrim, page, pageSize)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, rr := range resp.Data {
+			if strings.ToUpper(rr.Type) != "TXT" || !p.NamePolicy.allowsChallenge(rr.Name) {
+				continue
+			}
+			out = append(out, rr)
+		}
+
+		if resp.LastPage <= page || resp.LastPage == 0 {
+			break
+		}
+		page++
+	}
+
+	return out, nil
+}
+
+// txtRecord builds the libdns record for a TXT value stored at the absolute
+// name fqdn, the same way GetRecords reports it, so that records returned by
+// AppendRecords and DeleteRecords compare equal to what GetRecords returns.
+func (p *Provider) txtRecord(zoneTrim, fqdn, text string, ttl int) libdns.TXT {
+	nameRel := libdns.RelativeName(fqdn, zoneTrim+".")
+	if p.UnicodeNames {
+		nameRel = toUnicodeName(nameRel)
+	}
+	return libdns.TXT{Name: nameRel, Text: text, TTL: timeSeconds(ttl)}
+}
+
+// txtChange is one validated record of an append or delete call.
+type txtChange struct {
+	index int // position in the caller's slice
+	fqdn  string
+	txt   string
+	ttl   int
+}
+
+// groupTXTChanges validates recs and groups them by rrset name, in the order
+// the names first appear.
+func groupTXTChanges(zoneTrim string, recs []libdns.Record, policy NamePolicy) ([][]txtChange, error) {
+	var (
+		groups [][]txtChange
+		index  = map[string]int{}
+	)
+	for n, r := range recs {
+		fqdn, txt, ttl, err := ensureAcmeTXT(zoneTrim, r, policy)
+		if err != nil {
+			return nil, err
+		}
+		c := txtChange{index: n, fqdn: fqdn, txt: normalizeTXT(txt), ttl: ttl}
+
+		key := normalizeName(fqdn)
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], c)
+	}
+	return groups, nil
+}
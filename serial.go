@@ -0,0 +1,273 @@
+package rcodezeroacme
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kagescode/libdns-rcodezeroacme/internal/dnsquery"
+)
+
+// serialPollInterval is how often WaitForSerial re-queries lagging servers.
+var serialPollInterval = 2 * time.Second
+
+// serialChangeTimeout bounds how long a change waits for the authoritative
+// servers to serve a newer SOA serial when the zone endpoint is refused.
+var serialChangeTimeout = 30 * time.Second
+
+// serialSource is where the serial of a zone's changes is read from.
+type serialSource int
+
+const (
+	sourceUnknown serialSource = iota
+	sourceAPI                  // the zone endpoint
+	sourceSOA                  // SOA queries; the zone endpoint refused the token
+)
+
+// serialTracker remembers the newest zone serial known to contain a change
+// made by this provider, and where each zone's serial is read from.
+type serialTracker struct {
+	mu      sync.Mutex
+	serials map[string]uint32
+	sources map[string]serialSource
+}
+
+func (t *serialTracker) record(zone string, serial uint32) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.serials == nil {
+		t.serials = map[string]uint32{}
+	}
+	if old, ok := t.serials[zone]; !ok || !dnsquery.SerialAtLeast(old, serial) {
+		t.serials[zone] = serial
+	}
+}
+
+// forget drops the serial of zone, so LastSerial doesn't report a serial
+// that predates the latest change.
+func (t *serialTracker) forget(zone string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.serials, zone)
+}
+
+func (t *serialTracker) source(zone string) serialSource {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.sources[zone]
+}
+
+func (t *serialTracker) setSource(zone string, src serialSource) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.sources == nil {
+		t.sources = map[string]serialSource{}
+	}
+	t.sources[zone] = src
+}
+
+// LastSerial returns the zone serial that contains the provider's most
+// recent change to zone. It is only tracked with TrackSerial set; ok is
+// false if no change was made or the serial could not be determined.
+//
+// If the token may not read the zone endpoint, the serial is the first one
+// newer than the serial before the change that an authoritative server
+// served.
+func (p *Provider) LastSerial(zone string) (serial uint32, ok bool) {
+	zoneTrim, err := trimZone(zone)
+	if err != nil {
+		return 0, false
+	}
+	p.serials.mu.Lock()
+	defer p.serials.mu.Unlock()
+	serial, ok = p.serials.serials[normalizeName(zoneTrim)]
+	return serial, ok
+}
+
+// WaitForSerial blocks until every authoritative server of zone serves a
+// SOA serial at or past serial (in serial number arithmetic), or ctx is
+// done. Servers are taken from Nameservers or, if that is empty, from the
+// zone's NS records.
+func (p *Provider) WaitForSerial(ctx context.Context, zone string, serial uint32) error {
+	zoneTrim, err := trimZone(zone)
+	if err != nil {
+		return err
+	}
+	servers, err := p.nameservers(ctx, zoneTrim)
+	if err != nil {
+		return err
+	}
+
+	// Last observation per lagging server. A serial seen earlier is more
+	// useful in the final error than a query cut short by ctx.
+	type seen struct {
+		serial uint32
+		ok     bool
+		err    error
+	}
+	pending := map[string]seen{}
+	for _, s := range servers {
+		pending[s] = seen{}
+	}
+
+	ticker := time.NewTicker(serialPollInterval)
+	defer ticker.Stop()
+	for {
+		for server, last := range pending {
+			got, err := dnsquery.SOASerial(ctx, server, zoneTrim)
+			switch {
+			case err != nil:
+				last.err = err
+				pending[server] = last
+			case dnsquery.SerialAtLeast(got, serial):
+				delete(pending, server)
+			default:
+				pending[server] = seen{serial: got, ok: true}
+			}
+		}
+		if len(pending) == 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			lagging := make([]string, 0, len(pending))
+			for server, last := range pending {
+				switch {
+				case last.ok:
+					lagging = append(lagging, fmt.Sprintf("%s: serial %d", server, last.serial))
+				case last.err != nil:
+					lagging = append(lagging, fmt.Sprintf("%s: %v", server, last.err))
+				default:
+					lagging = append(lagging, server+": not queried")
+				}
+			}
+			sort.Strings(lagging)
+			return fmt.Errorf("wait for serial %d of %s: %w (%s)", serial, zoneTrim, ctx.Err(), strings.Join(lagging, "; "))
+		case <-ticker.C:
+		}
+	}
+}
+
+// patch sends sets and, with TrackSerial, records the serial containing
+// the change: from the zone endpoint if the token may read it, otherwise
+// the first SOA serial newer than the one served before the change. The
+// zone endpoint is given up for a zone only once it refuses the token;
+// other errors leave the serial of that change unknown. Failing to
+// determine the serial doesn't fail the change.
+func (p *Provider) patch(ctx context.Context, zoneTrim string, sets []UpdateRRSet) error {
+	if !p.TrackSerial {
+		_, err := p.client.PatchRRsets(ctx, zoneTrim, sets)
+		return err
+	}
+	zone := normalizeName(zoneTrim)
+
+	// Find out before the change whether the zone endpoint is readable,
+	// as the SOA fallback needs the serial from before the change.
+	src := p.serials.source(zone)
+	if src == sourceUnknown {
+		p.apiSerial(ctx, zone, zoneTrim)
+		src = p.serials.source(zone)
+	}
+	var (
+		before    uint32
+		hasBefore bool
+	)
+	if src == sourceSOA {
+		before, hasBefore = p.soaSerial(ctx, zoneTrim)
+	}
+
+	if _, err := p.client.PatchRRsets(ctx, zoneTrim, sets); err != nil {
+		return err
+	}
+
+	var (
+		serial uint32
+		ok     bool
+	)
+	switch {
+	case src != sourceSOA:
+		serial, ok = p.apiSerial(ctx, zone, zoneTrim)
+	case hasBefore:
+		serial, ok = p.newerSOASerial(ctx, zoneTrim, before)
+	}
+	if ok {
+		p.serials.record(zone, serial)
+	} else {
+		p.serials.forget(zone)
+	}
+	return nil
+}
+
+// apiSerial reads the zone serial from the zone endpoint. A refused token
+// switches the zone to SOA queries; other errors are taken as transient.
+func (p *Provider) apiSerial(ctx context.Context, zone, zoneTrim string) (uint32, bool) {
+	z, err := p.client.GetZone(ctx, zoneTrim)
+	switch {
+	case err == nil && z.Serial > 0:
+		p.serials.setSource(zone, sourceAPI)
+		return uint32(z.Serial), true
+	case errors.Is(err, ErrUnauthorized):
+		p.serials.setSource(zone, sourceSOA)
+	}
+	return 0, false
+}
+
+// newerSOASerial polls the zone's servers until one serves a serial newer
+// than before, for at most serialChangeTimeout.
+func (p *Provider) newerSOASerial(ctx context.Context, zoneTrim string, before uint32) (uint32, bool) {
+	ctx, cancel := context.WithTimeout(ctx, serialChangeTimeout)
+	defer cancel()
+	ticker := time.NewTicker(serialPollInterval)
+	defer ticker.Stop()
+	for {
+		if got, ok := p.soaSerial(ctx, zoneTrim); ok && dnsquery.SerialAtLeast(got, before+1) {
+			return got, true
+		}
+		select {
+		case <-ctx.Done():
+			return 0, false
+		case <-ticker.C:
+		}
+	}
+}
+
+// soaSerial returns the highest SOA serial among the zone's servers.
+func (p *Provider) soaSerial(ctx context.Context, zoneTrim string) (uint32, bool) {
+	servers, err := p.nameservers(ctx, zoneTrim)
+	if err != nil {
+		return 0, false
+	}
+	var (
+		max uint32
+		ok  bool
+	)
+	for _, s := range servers {
+		got, err := dnsquery.SOASerial(ctx, s, zoneTrim)
+		if err != nil {
+			continue
+		}
+		if !ok || !dnsquery.SerialAtLeast(max, got) {
+			max, ok = got, true
+		}
+	}
+	return max, ok
+}
+
+func (p *Provider) nameservers(ctx context.Context, zoneTrim string) ([]string, error) {
+	if len(p.Nameservers) > 0 {
+		return p.Nameservers, nil
+	}
+	servers, err := dnsquery.Nameservers(ctx, zoneTrim)
+	if err != nil {
+		return nil, fmt.Errorf("nameservers of %s: %w", zoneTrim, err)
+	}
+	if len(servers) == 0 {
+		return nil, fmt.Errorf("nameservers of %s: none found", zoneTrim)
+	}
+	return servers, nil
+}
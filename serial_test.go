@@ -0,0 +1,210 @@
+package rcodezeroacme
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+
+	"github.com/kagescode/libdns-rcodezeroacme/internal/apitest"
+)
+
+// soaServer answers SOA queries on a local UDP socket with the current
+// value of serial.
+func soaServer(t *testing.T, serial *atomic.Uint32) string {
+	t.Helper()
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("no local UDP: %v", err)
+	}
+	t.Cleanup(func() { pc.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := pc.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			resp := append([]byte(nil), buf[:n]...)
+			resp[2] |= 0x84
+			binary.BigEndian.PutUint16(resp[6:], 1)
+			resp = append(resp, 0xc0, 12, 0, 6, 0, 1, 0, 0, 0, 60)
+			rdata := []byte{0xc0, 12, 0xc0, 12}
+			rdata = binary.BigEndian.AppendUint32(rdata, serial.Load())
+			rdata = append(rdata, make([]byte, 16)...)
+			resp = binary.BigEndian.AppendUint16(resp, uint16(len(rdata)))
+			resp = append(resp, rdata...)
+			pc.WriteTo(resp, addr)
+		}
+	}()
+	return pc.LocalAddr().String()
+}
+
+func TestWaitForSerial(t *testing.T) {
+	old := serialPollInterval
+	serialPollInterval = 10 * time.Millisecond
+	defer func() { serialPollInterval = old }()
+
+	var a, b atomic.Uint32
+	a.Store(100)
+	b.Store(99)
+	p := &Provider{APIToken: apitest.Token, Nameservers: []string{soaServer(t, &a), soaServer(t, &b)}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	err := p.WaitForSerial(ctx, "example.com.", 100)
+	cancel()
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "serial 99") {
+		t.Fatalf("WaitForSerial with lagging server = %v", err)
+	}
+
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		b.Store(101)
+	}()
+	ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := p.WaitForSerial(ctx, "example.com.", 100); err != nil {
+		t.Fatalf("WaitForSerial: %v", err)
+	}
+}
+
+func TestTrackSerial_FromAPI(t *testing.T) {
+	api := newFakeACME()
+	hc := fakeHTTP(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Path == "/api/v1/zones/example.com" {
+			return apitest.JSONResponse(200, `{"domain":"example.com","serial":2026101805}`), nil
+		}
+		return api.Do(req)
+	})
+	p := &Provider{APIToken: apitest.Token, HTTPClient: hc, TrackSerial: true}
+
+	if _, ok := p.LastSerial("example.com"); ok {
+		t.Fatal("serial known before any change")
+	}
+	_, err := p.AppendRecords(context.Background(), "example.com.", []libdns.Record{
+		libdns.TXT{Name: "_acme-challenge", Text: "v"},
+	})
+	if err != nil {
+		t.Fatalf("AppendRecords: %v", err)
+	}
+	if got, ok := p.LastSerial("example.com."); !ok || got != 2026101805 {
+		t.Errorf("LastSerial = %d, %v", got, ok)
+	}
+}
+
+func TestTrackSerial_FallsBackToSOA(t *testing.T) {
+	old := serialPollInterval
+	serialPollInterval = 10 * time.Millisecond
+	defer func() { serialPollInterval = old }()
+
+	for _, status := range []int{401, 403} {
+		api := newFakeACME()
+		var soa atomic.Uint32
+		soa.Store(41)
+		var zoneReads atomic.Int32
+		hc := fakeHTTP(func(req *http.Request) (*http.Response, error) {
+			if req.URL.Path == "/api/v1/zones/example.com" {
+				zoneReads.Add(1)
+				return apitest.JSONResponse(status, `{"status":"failed","message":"no"}`), nil
+			}
+			if req.Method == http.MethodPatch {
+				// The servers serve the change only after the PATCH.
+				defer soa.Add(1)
+			}
+			return api.Do(req)
+		})
+		p := &Provider{APIToken: apitest.Token, HTTPClient: hc, TrackSerial: true, Nameservers: []string{soaServer(t, &soa)}}
+		ctx := context.Background()
+
+		rec := []libdns.Record{libdns.TXT{Name: "_acme-challenge", Text: "v"}}
+		if _, err := p.AppendRecords(ctx, "example.com.", rec); err != nil {
+			t.Fatalf("AppendRecords: %v", err)
+		}
+		if got, ok := p.LastSerial("example.com"); !ok || got != 42 {
+			t.Errorf("status %d: LastSerial = %d, %v; want 42", status, got, ok)
+		}
+
+		if _, err := p.DeleteRecords(ctx, "example.com.", rec); err != nil {
+			t.Fatalf("DeleteRecords: %v", err)
+		}
+		if got, ok := p.LastSerial("example.com"); !ok || got != 43 {
+			t.Errorf("status %d: LastSerial = %d, %v; want 43", status, got, ok)
+		}
+		if n := zoneReads.Load(); n != 1 {
+			t.Errorf("status %d: zone endpoint read %d times, want 1", status, n)
+		}
+	}
+}
+
+func TestTrackSerial_SOAMustAdvance(t *testing.T) {
+	oldPoll, oldTimeout := serialPollInterval, serialChangeTimeout
+	serialPollInterval, serialChangeTimeout = 10*time.Millisecond, 50*time.Millisecond
+	defer func() { serialPollInterval, serialChangeTimeout = oldPoll, oldTimeout }()
+
+	api := newFakeACME()
+	hc := fakeHTTP(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Path == "/api/v1/zones/example.com" {
+			return apitest.JSONResponse(403, `{"status":"failed","message":"no"}`), nil
+		}
+		return api.Do(req)
+	})
+	var soa atomic.Uint32
+	soa.Store(41)
+	p := &Provider{APIToken: apitest.Token, HTTPClient: hc, TrackSerial: true, Nameservers: []string{soaServer(t, &soa)}}
+
+	// The servers still serve the serial from before the change.
+	rec := []libdns.Record{libdns.TXT{Name: "_acme-challenge", Text: "v"}}
+	if _, err := p.AppendRecords(context.Background(), "example.com.", rec); err != nil {
+		t.Fatalf("AppendRecords: %v", err)
+	}
+	if got, ok := p.LastSerial("example.com"); ok {
+		t.Errorf("LastSerial = %d, want none while the SOA serial hasn't advanced", got)
+	}
+}
+
+func TestTrackSerial_TransientErrorsKeepAPI(t *testing.T) {
+	for _, status := range []int{404, 500} {
+		api := newFakeACME()
+		var zoneReads atomic.Int32
+		failing := true
+		hc := fakeHTTP(func(req *http.Request) (*http.Response, error) {
+			if req.URL.Path == "/api/v1/zones/example.com" {
+				zoneReads.Add(1)
+				if failing {
+					return apitest.JSONResponse(status, `{"status":"failed","message":"no"}`), nil
+				}
+				return apitest.JSONResponse(200, `{"domain":"example.com","serial":2026101805}`), nil
+			}
+			return api.Do(req)
+		})
+		p := &Provider{APIToken: apitest.Token, HTTPClient: hc, TrackSerial: true}
+		ctx := context.Background()
+
+		rec := []libdns.Record{libdns.TXT{Name: "_acme-challenge", Text: "v"}}
+		if _, err := p.AppendRecords(ctx, "example.com.", rec); err != nil {
+			t.Fatalf("AppendRecords: %v", err)
+		}
+		if got, ok := p.LastSerial("example.com"); ok {
+			t.Errorf("status %d: LastSerial = %d, want none", status, got)
+		}
+
+		failing = false
+		if _, err := p.DeleteRecords(ctx, "example.com.", rec); err != nil {
+			t.Fatalf("DeleteRecords: %v", err)
+		}
+		if got, ok := p.LastSerial("example.com"); !ok || got != 2026101805 {
+			t.Errorf("status %d: LastSerial = %d, %v; want the zone endpoint's serial", status, got, ok)
+		}
+		if n := zoneReads.Load(); n < 3 {
+			t.Errorf("status %d: zone endpoint read %d times, want it asked again after the failure", status, n)
+		}
+	}
+}
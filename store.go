@@ -0,0 +1,84 @@
+package rcodezeroacme
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// OwnedValue records a challenge value created by a provider.
+type OwnedValue struct {
+	Zone    string    `json:"zone"`
+	Name    string    `json:"name"`
+	Value   string    `json:"value"`
+	Creator string    `json:"creator,omitempty"`
+	Created time.Time `json:"created"`
+}
+
+func (o OwnedValue) key() string { return ownedKey(o.Zone, o.Name, o.Value) }
+
+func ownedKey(zone, name, value string) string {
+	return normalizeName(zone) + "|" + normalizeName(name) + "|" + value
+}
+
+// Store keeps track of the challenge values a provider created, so cleanup
+// paths only remove values they own. Zone and name are compared
+// case-insensitively and without trailing dots.
+type Store interface {
+	Put(ctx context.Context, v OwnedValue) error
+	Delete(ctx context.Context, zone, name, value string) error
+	Get(ctx context.Context, zone, name, value string) (OwnedValue, bool, error)
+	List(ctx context.Context, zone string) ([]OwnedValue, error)
+}
+
+// MemoryStore is a Store held in process memory. Its zero value is ready
+// to use. Entries are lost on restart.
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]OwnedValue
+}
+
+func (m *MemoryStore) Put(_ context.Context, v OwnedValue) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.entries == nil {
+		m.entries = map[string]OwnedValue{}
+	}
+	m.entries[v.key()] = v
+	return nil
+}
+
+func (m *MemoryStore) Delete(_ context.Context, zone, name, value string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.entries, ownedKey(zone, name, value))
+	return nil
+}
+
+func (m *MemoryStore) Get(_ context.Context, zone, name, value string) (OwnedValue, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, ok := m.entries[ownedKey(zone, name, value)]
+	return v, ok, nil
+}
+
+func (m *MemoryStore) List(_ context.Context, zone string) ([]OwnedValue, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return filterZone(m.entries, zone), nil
+}
+
+// filterZone returns the entries of zone (all entries if zone is empty),
+// sorted by creation time.
+func filterZone(entries map[string]OwnedValue, zone string) []OwnedValue {
+	zone = normalizeName(zone)
+	var out []OwnedValue
+	for _, v := range entries {
+		if zone == "" || normalizeName(v.Zone) == zone {
+			out = append(out, v)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Created.Before(out[j].Created) })
+	return out
+}
@@ -0,0 +1,290 @@
+package rcodezeroacme
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// JSONFileStore is a Store persisted as a single JSON document. The file
+// is re-read on every operation and replaced atomically on writes, which
+// suits the handful of entries an ACME issuer keeps at a time.
+//
+// Updates are serialized within one process only: two processes sharing
+// the file can overwrite each other's changes. Give each process its own
+// file, or implement Store on a shared database.
+type JSONFileStore struct {
+	Path string
+
+	mu sync.Mutex
+}
+
+func (f *JSONFileStore) Put(_ context.Context, v OwnedValue) error {
+	return f.update(func(entries map[string]OwnedValue) { entries[v.key()] = v })
+}
+
+func (f *JSONFileStore) Delete(_ context.Context, zone, name, value string) error {
+	return f.update(func(entries map[string]OwnedValue) { delete(entries, ownedKey(zone, name, value)) })
+}
+
+func (f *JSONFileStore) Get(_ context.Context, zone, name, value string) (OwnedValue, bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	entries, err := f.load()
+	if err != nil {
+		return OwnedValue{}, false, err
+	}
+	v, ok := entries[ownedKey(zone, name, value)]
+	return v, ok, nil
+}
+
+func (f *JSONFileStore) List(_ context.Context, zone string) ([]OwnedValue, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	entries, err := f.load()
+	if err != nil {
+		return nil, err
+	}
+	return filterZone(entries, zone), nil
+}
+
+func (f *JSONFileStore) update(fn func(map[string]OwnedValue)) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	entries, err := f.load()
+	if err != nil {
+		return err
+	}
+	fn(entries)
+
+	list := filterZone(entries, "")
+	raw, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return fmt.Errorf("json store: encode: %w", err)
+	}
+	return writeFileAtomic(f.Path, raw)
+}
+
+func (f *JSONFileStore) load() (map[string]OwnedValue, error) {
+	if f.Path == "" {
+		return nil, fmt.Errorf("json store: empty path")
+	}
+	entries := map[string]OwnedValue{}
+	raw, err := os.ReadFile(f.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("json store: %w", err)
+	}
+	if len(raw) == 0 {
+		return entries, nil
+	}
+
+	var list []OwnedValue
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return nil, fmt.Errorf("json store: decode %s: %w", f.Path, err)
+	}
+	for _, v := range list {
+		entries[v.key()] = v
+	}
+	return entries, nil
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// JournalStore is an embedded single-file Store: every change is appended
+// to the file as one JSON line and the file is replayed into memory on
+// first use. The journal is compacted once it holds more than
+// CompactAfter obsolete lines (default 1000). Like JSONFileStore, the
+// file must not be shared between processes.
+type JournalStore struct {
+	Path         string
+	CompactAfter int
+
+	mu       sync.Mutex
+	loaded   bool
+	entries  map[string]OwnedValue
+	obsolete int
+}
+
+type journalOp struct {
+	Op    string     `json:"op"`
+	Entry OwnedValue `json:"entry"`
+}
+
+const (
+	journalPut    = "put"
+	journalDelete = "delete"
+)
+
+func (j *JournalStore) Put(_ context.Context, v OwnedValue) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if err := j.open(); err != nil {
+		return err
+	}
+	if _, ok := j.entries[v.key()]; ok {
+		j.obsolete++
+	}
+	j.entries[v.key()] = v
+	return j.append(journalOp{Op: journalPut, Entry: v})
+}
+
+func (j *JournalStore) Delete(_ context.Context, zone, name, value string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if err := j.open(); err != nil {
+		return err
+	}
+	key := ownedKey(zone, name, value)
+	if _, ok := j.entries[key]; !ok {
+		return nil
+	}
+	delete(j.entries, key)
+	j.obsolete += 2
+	return j.append(journalOp{Op: journalDelete, Entry: OwnedValue{Zone: zone, Name: name, Value: value}})
+}
+
+func (j *JournalStore) Get(_ context.Context, zone, name, value string) (OwnedValue, bool, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if err := j.open(); err != nil {
+		return OwnedValue{}, false, err
+	}
+	v, ok := j.entries[ownedKey(zone, name, value)]
+	return v, ok, nil
+}
+
+func (j *JournalStore) List(_ context.Context, zone string) ([]OwnedValue, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if err := j.open(); err != nil {
+		return nil, err
+	}
+	return filterZone(j.entries, zone), nil
+}
+
+// open replays the journal into memory once.
+func (j *JournalStore) open() error {
+	if j.loaded {
+		return nil
+	}
+	if j.Path == "" {
+		return fmt.Errorf("journal store: empty path")
+	}
+	j.entries = map[string]OwnedValue{}
+	j.obsolete = 0
+
+	f, err := os.Open(j.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		j.loaded = true
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("journal store: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		var op journalOp
+		if err := json.Unmarshal(sc.Bytes(), &op); err != nil {
+			// A torn final line from a crash mid-write; everything before
+			// it is intact.
+			j.obsolete++
+			continue
+		}
+		key := op.Entry.key()
+		switch op.Op {
+		case journalPut:
+			if _, ok := j.entries[key]; ok {
+				j.obsolete++
+			}
+			j.entries[key] = op.Entry
+		case journalDelete:
+			delete(j.entries, key)
+			j.obsolete += 2
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("journal store: read %s: %w", j.Path, err)
+	}
+
+	j.loaded = true
+	return nil
+}
+
+func (j *JournalStore) append(op journalOp) error {
+	limit := j.CompactAfter
+	if limit <= 0 {
+		limit = 1000
+	}
+	if j.obsolete > limit {
+		return j.compact()
+	}
+
+	line, err := json.Marshal(op)
+	if err != nil {
+		return fmt.Errorf("journal store: encode: %w", err)
+	}
+	f, err := os.OpenFile(j.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("journal store: %w", err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("journal store: write: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("journal store: sync: %w", err)
+	}
+	return f.Close()
+}
+
+// compact rewrites the journal with one put line per live entry.
+func (j *JournalStore) compact() error {
+	var buf []byte
+	for _, v := range filterZone(j.entries, "") {
+		line, err := json.Marshal(journalOp{Op: journalPut, Entry: v})
+		if err != nil {
+			return fmt.Errorf("journal store: encode: %w", err)
+		}
+		buf = append(buf, line...)
+		buf = append(buf, '\n')
+	}
+	if err := writeFileAtomic(j.Path, buf); err != nil {
+		return fmt.Errorf("journal store: compact: %w", err)
+	}
+	j.obsolete = 0
+	return nil
+}
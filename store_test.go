@@ -0,0 +1,46 @@
+package rcodezeroacme
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileStores_PersistAcrossInstances(t *testing.T) {
+	dir := t.TempDir()
+	cases := map[string]func() Store{
+		"json":    func() Store { return &JSONFileStore{Path: filepath.Join(dir, "owned.json")} },
+		"journal": func() Store { return &JournalStore{Path: filepath.Join(dir, "owned.log"), CompactAfter: 1} },
+	}
+
+	for name, open := range cases {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			s := open()
+			now := time.Now().UTC().Truncate(time.Second)
+			for _, v := range []string{"a", "b", "c"} {
+				err := s.Put(ctx, OwnedValue{Zone: "example.com", Name: "_acme-challenge.example.com.", Value: v, Creator: "node1", Created: now})
+				if err != nil {
+					t.Fatalf("Put: %v", err)
+				}
+			}
+			if err := s.Delete(ctx, "Example.com.", "_ACME-challenge.example.com", "b"); err != nil {
+				t.Fatalf("Delete: %v", err)
+			}
+
+			reopened := open()
+			list, err := reopened.List(ctx, "example.com")
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+			if len(list) != 2 {
+				t.Fatalf("expected 2 entries after reopen, got %+v", list)
+			}
+			v, ok, err := reopened.Get(ctx, "example.com", "_acme-challenge.example.com", "c")
+			if err != nil || !ok || v.Creator != "node1" || !v.Created.Equal(now) {
+				t.Fatalf("Get: %+v %v %v", v, ok, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package rcodezeroacme
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kagescode/libdns-rcodezeroacme/internal/transport"
+)
+
+// TokenSource supplies the API bearer token. It is consulted on every
+// request, so implementations may return a different token over time.
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// StaticToken is a TokenSource that always returns the same token.
+type StaticToken string
+
+func (s StaticToken) Token(context.Context) (string, error) {
+	tok := strings.TrimSpace(string(s))
+	if tok == "" {
+		return "", fmt.Errorf("APIToken is required")
+	}
+	return tok, nil
+}
+
+// EnvToken reads the token from the named environment variable on every call.
+type EnvToken struct {
+	Name string
+}
+
+func (e EnvToken) Token(context.Context) (string, error) {
+	if e.Name == "" {
+		return "", fmt.Errorf("env token: empty variable name")
+	}
+	tok := strings.TrimSpace(os.Getenv(e.Name))
+	if tok == "" {
+		return "", fmt.Errorf("env token: %s is empty or unset", e.Name)
+	}
+	return tok, nil
+}
+
+// FileToken reads the token from a file and re-reads it whenever the
+// file's modification time or size changes (e.g. a rotated secret mount).
+type FileToken struct {
+	Path string
+
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+	token   string
+}
+
+func (f *FileToken) Token(context.Context) (string, error) {
+	if f.Path == "" {
+		return "", fmt.Errorf("file token: empty path")
+	}
+	fi, err := os.Stat(f.Path)
+	if err != nil {
+		return "", fmt.Errorf("file token: %w", err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.token != "" && fi.ModTime().Equal(f.modTime) && fi.Size() == f.size {
+		return f.token, nil
+	}
+
+	raw, err := os.ReadFile(f.Path)
+	if err != nil {
+		return "", fmt.Errorf("file token: %w", err)
+	}
+	tok := strings.TrimSpace(string(raw))
+	if tok == "" {
+		return "", fmt.Errorf("file token: %s is empty", f.Path)
+	}
+
+	f.token = tok
+	f.modTime = fi.ModTime()
+	f.size = fi.Size()
+	return tok, nil
+}
+
+// CommandToken runs an external command and uses its trimmed stdout as the
+// token. The result is cached for TTL; a zero TTL runs the command on
+// every request.
+type CommandToken struct {
+	Command string
+	Args    []string
+	TTL     time.Duration
+
+	mu      sync.Mutex
+	fetched time.Time
+	token   string
+}
+
+func (c *CommandToken) Token(ctx context.Context) (string, error) {
+	if c.Command == "" {
+		return "", fmt.Errorf("command token: empty command")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token != "" && c.TTL > 0 && time.Since(c.fetched) < c.TTL {
+		return c.token, nil
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, c.Command, c.Args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("command token: %s: %w: %s", c.Command, err, strings.TrimSpace(stderr.String()))
+	}
+	tok := strings.TrimSpace(string(out))
+	if tok == "" {
+		return "", fmt.Errorf("command token: %s produced no output", c.Command)
+	}
+
+	c.token = tok
+	c.fetched = time.Now()
+	return tok, nil
+}
+
+// ErrNoToken is returned when no API token is configured for a request.
+var ErrNoToken = errors.New("no API token configured")
+
+// withZone records the zone a request is made for, so TokenSources can
+// select a zone-specific token.
+func withZone(ctx context.Context, zone string) context.Context {
+	return transport.WithZone(ctx, zone)
+}
+
+// ZoneFromContext returns the zone (without trailing dot) an API request is
+// being made for, if any.
+func ZoneFromContext(ctx context.Context) (string, bool) {
+	return transport.ZoneFromContext(ctx)
+}
+
+// ZoneTokens selects a TokenSource by the zone of the request. A key matches
+// the zone itself and any zone below it ("example.com" matches
+// "example.com" and "sub.example.com"); the longest matching key wins.
+// Default is used when no key matches.
+type ZoneTokens struct {
+	Zones   map[string]TokenSource
+	Default TokenSource
+}
+
+func (z ZoneTokens) Token(ctx context.Context) (string, error) {
+	zone, _ := ZoneFromContext(ctx)
+	if ts := z.lookup(zone); ts != nil {
+		return ts.Token(ctx)
+	}
+	if z.Default != nil {
+		return z.Default.Token(ctx)
+	}
+	return "", fmt.Errorf("%w for zone %q", ErrNoToken, zone)
+}
+
+func (z ZoneTokens) lookup(zone string) TokenSource {
+	ts, _ := longestZoneMatch(z.Zones, zone)
+	return ts
+}
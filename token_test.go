@@ -0,0 +1,163 @@
+package rcodezeroacme
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kagescode/libdns-rcodezeroacme/internal/apitest"
+)
+
+func TestFileToken_RereadsOnChange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte("first\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	ts := &FileToken{Path: path}
+	tok, err := ts.Token(context.Background())
+	if err != nil || tok != "first" {
+		t.Fatalf("got %q, %v", tok, err)
+	}
+
+	if err := os.WriteFile(path, []byte("second-token\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	future := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, future, future); err != nil {
+		t.Fatal(err)
+	}
+
+	tok, err = ts.Token(context.Background())
+	if err != nil || tok != "second-token" {
+		t.Fatalf("expected rotated token, got %q, %v", tok, err)
+	}
+}
+
+func TestEnvToken(t *testing.T) {
+	t.Setenv("RCODEZERO_TEST_TOKEN", "  env-token-value\n")
+	tok, err := EnvToken{Name: "RCODEZERO_TEST_TOKEN"}.Token(context.Background())
+	if err != nil || tok != "env-token-value" {
+		t.Fatalf("got %q, %v", tok, err)
+	}
+
+	t.Setenv("RCODEZERO_TEST_TOKEN", "")
+	if _, err := (EnvToken{Name: "RCODEZERO_TEST_TOKEN"}).Token(context.Background()); err == nil || !strings.Contains(err.Error(), "RCODEZERO_TEST_TOKEN") {
+		t.Errorf("empty variable: err = %v", err)
+	}
+	if _, err := (EnvToken{}).Token(context.Background()); err == nil {
+		t.Errorf("expected error for empty variable name")
+	}
+}
+
+func TestCommandToken_CachesForTTL(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no sh")
+	}
+	// Each run appends a line to count and prints the number of runs.
+	count := filepath.Join(t.TempDir(), "count")
+	script := `echo x >> "$0"; echo "token-$(wc -l < "$0" | tr -d ' ')"`
+	ctx := context.Background()
+
+	ts := &CommandToken{Command: "sh", Args: []string{"-c", script, count}, TTL: time.Hour}
+	for i := 0; i < 3; i++ {
+		if tok, err := ts.Token(ctx); err != nil || tok != "token-1" {
+			t.Fatalf("call %d: got %q, %v", i, tok, err)
+		}
+	}
+
+	ts.TTL = 0
+	if tok, err := ts.Token(ctx); err != nil || tok != "token-2" {
+		t.Fatalf("zero TTL: got %q, %v; want the command to run again", tok, err)
+	}
+}
+
+func TestCommandToken_Failure(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no sh")
+	}
+	ctx := context.Background()
+
+	ts := &CommandToken{Command: "sh", Args: []string{"-c", "echo vault is sealed >&2; exit 3"}}
+	_, err := ts.Token(ctx)
+	if err == nil || !strings.Contains(err.Error(), "vault is sealed") || !strings.Contains(err.Error(), "exit status 3") {
+		t.Errorf("failing command: err = %v", err)
+	}
+
+	ts = &CommandToken{Command: "sh", Args: []string{"-c", "true"}}
+	if _, err := ts.Token(ctx); err == nil || !strings.Contains(err.Error(), "no output") {
+		t.Errorf("silent command: err = %v", err)
+	}
+}
+
+// rotatingToken returns a new token on every call.
+type rotatingToken struct{ calls atomic.Int32 }
+
+func (r *rotatingToken) Token(context.Context) (string, error) {
+	return fmt.Sprintf("rotating-token-%d", r.calls.Add(1)), nil
+}
+
+func TestClient_AsksTokenSourcePerRequest(t *testing.T) {
+	var auth []string
+	hc := fakeHTTP(func(req *http.Request) (*http.Response, error) {
+		auth = append(auth, req.Header.Get("Authorization"))
+		return apitest.JSONResponse(200, `{"data":[],"last_page":1}`), nil
+	})
+	ts := &rotatingToken{}
+	c, err := NewClientWithTokenSource(ts, "", hc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := c.GetRRsets(context.Background(), "example.com", 1, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(auth) != 2 || auth[0] != "Bearer rotating-token-1" || auth[1] != "Bearer rotating-token-2" {
+		t.Errorf("Authorization headers = %q", auth)
+	}
+}
+
+func TestZoneTokens_LongestSuffixWins(t *testing.T) {
+	zt := ZoneTokens{
+		Zones: map[string]TokenSource{
+			"example.com.":      StaticToken("parent"),
+			"sub.example.com":   StaticToken("child"),
+			"other.example.org": StaticToken("other"),
+		},
+		Default: StaticToken("default"),
+	}
+
+	cases := map[string]string{
+		"example.com":       "parent",
+		"a.example.com":     "parent",
+		"sub.example.com":   "child",
+		"x.sub.example.com": "child",
+		"notexample.com":    "default",
+		"example.org":       "default",
+	}
+	for zone, want := range cases {
+		got, err := zt.Token(withZone(context.Background(), zone))
+		if err != nil || got != want {
+			t.Errorf("zone %q: got %q, %v; want %q", zone, got, err, want)
+		}
+	}
+
+	// Unicode keys match the punycoded zone the provider passes along.
+	zt.Zones["bücher.example"] = StaticToken("idn")
+	if got, err := zt.Token(withZone(context.Background(), "shop.xn--bcher-kva.example")); err != nil || got != "idn" {
+		t.Errorf("unicode key: got %q, %v; want %q", got, err, "idn")
+	}
+
+	zt.Default = nil
+	if _, err := zt.Token(withZone(context.Background(), "example.net")); err == nil {
+		t.Errorf("expected error for unmapped zone without default")
+	}
+}
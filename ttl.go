@@ -0,0 +1,113 @@
+package rcodezeroacme
+
+import (
+	"strings"
+	"time"
+)
+
+// defaultTTL is the TTL used for records that don't specify one.
+const defaultTTL = 60 * time.Second
+
+// defaultPersistTTL is the TTL for DNS-PERSIST-01 records.
+const defaultPersistTTL = time.Hour
+
+// TTLPolicy decides the TTL written for challenge rrsets.
+type TTLPolicy struct {
+	// Default applies to records without a TTL. Zero means 60s.
+	Default time.Duration
+
+	// Min and Max clamp the TTL; zero means no bound.
+	Min time.Duration
+	Max time.Duration
+
+	// OverrideExisting writes the policy TTL when adding values to an
+	// existing rrset. By default the rrset keeps its current TTL, clamped
+	// to Min and Max.
+	OverrideExisting bool
+
+	// Zones holds per-zone policies. A key matches that zone and every zone
+	// below it; the longest match wins. Default, Min and Max left zero in a
+	// zone policy are taken from this one, and OverrideExisting applies if
+	// either sets it.
+	Zones map[string]TTLPolicy
+}
+
+// forZone returns the policy that applies to zone.
+func (tp TTLPolicy) forZone(zone string) TTLPolicy {
+	zp, ok := longestZoneMatch(tp.Zones, zone)
+	if !ok {
+		return tp
+	}
+	if zp.Default == 0 {
+		zp.Default = tp.Default
+	}
+	if zp.Min == 0 {
+		zp.Min = tp.Min
+	}
+	if zp.Max == 0 {
+		zp.Max = tp.Max
+	}
+	zp.OverrideExisting = zp.OverrideExisting || tp.OverrideExisting
+	return zp
+}
+
+// effective returns the TTL in seconds for a record that asked for
+// requested seconds (0 if unset).
+func (tp TTLPolicy) effective(requested int) int {
+	ttl := time.Duration(requested) * time.Second
+	if ttl <= 0 {
+		ttl = tp.Default
+	}
+	if ttl <= 0 {
+		ttl = defaultTTL
+	}
+	return tp.clamp(ttl)
+}
+
+// clamp returns ttl bounded by Min and Max, in seconds.
+func (tp TTLPolicy) clamp(ttl time.Duration) int {
+	if tp.Min > 0 && ttl < tp.Min {
+		ttl = tp.Min
+	}
+	if tp.Max > 0 && ttl > tp.Max {
+		ttl = tp.Max
+	}
+	return durationToSeconds(ttl)
+}
+
+// longestZoneMatch returns the value whose key is zone or the closest
+// parent of zone.
+func longestZoneMatch[T any](m map[string]T, zone string) (T, bool) {
+	var (
+		best    T
+		bestLen = -1
+	)
+	zone = asciiKey(zone)
+	if zone == "" {
+		return best, false
+	}
+	for key, v := range m {
+		// Keys may be written in Unicode; zones are compared punycoded.
+		k := asciiKey(key)
+		if k == "" {
+			continue
+		}
+		if zone != k && !strings.HasSuffix(zone, "."+k) {
+			continue
+		}
+		if len(k) > bestLen {
+			best, bestLen = v, len(k)
+		}
+	}
+	return best, bestLen >= 0
+}
+
+// asciiKey normalizes a zone map key to its lower-case ASCII form, or ""
+// if it is not a valid name.
+func asciiKey(name string) string {
+	a, err := toASCIIName(normalizeName(name))
+	if err != nil {
+		return ""
+	}
+	return normalizeName(a)
+}
@@ -0,0 +1,104 @@
+package rcodezeroacme
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+
+	"github.com/kagescode/libdns-rcodezeroacme/internal/apitest"
+)
+
+func TestTTLPolicy_Effective(t *testing.T) {
+	tp := TTLPolicy{
+		Min: 30 * time.Second,
+		Max: 300 * time.Second,
+		Zones: map[string]TTLPolicy{
+			"slow.example": {Default: 600 * time.Second},
+			"fast.example": {Max: 10 * time.Second, Min: 5 * time.Second},
+		},
+	}
+
+	cases := []struct {
+		zone      string
+		requested int
+		want      int
+	}{
+		{"example.com", 0, 60},
+		{"example.com", 10, 30},
+		{"example.com", 120, 120},
+		{"example.com", 3600, 300},
+		{"sub.slow.example", 0, 300},
+		{"slow.example", 5, 30},
+		{"fast.example", 0, 10},
+		{"fast.example", 1, 5},
+	}
+	for _, c := range cases {
+		if got := tp.forZone(c.zone).effective(c.requested); got != c.want {
+			t.Errorf("zone %s requested %d: got %d, want %d", c.zone, c.requested, got, c.want)
+		}
+	}
+}
+
+func TestAppendRecords_ClampsExistingTTL(t *testing.T) {
+	api := newFakeACME()
+	api.rrsets["_acme-challenge.example.com."] = &RRSet{
+		Name: "_acme-challenge.example.com.", Type: "TXT", TTL: 3600,
+		Records: []Record{{Content: `"existing"`}},
+	}
+	p := &Provider{APIToken: apitest.Token, HTTPClient: api, TTLPolicy: TTLPolicy{Max: 2 * time.Minute}}
+
+	got, err := p.AppendRecords(context.Background(), "example.com.", []libdns.Record{
+		libdns.TXT{Name: "_acme-challenge", Text: "new"},
+	})
+	if err != nil {
+		t.Fatalf("AppendRecords: %v", err)
+	}
+	if ttl := got[0].RR().TTL; ttl != 2*time.Minute {
+		t.Errorf("returned TTL = %s, want clamped 2m0s", ttl)
+	}
+	if ttl := api.rrsets["_acme-challenge.example.com."].TTL; ttl != 120 {
+		t.Errorf("rrset TTL = %d, want the existing 3600 clamped to 120", ttl)
+	}
+}
+
+func TestTTLPolicy_UnicodeZoneKey(t *testing.T) {
+	tp := TTLPolicy{Zones: map[string]TTLPolicy{"Bücher.example.": {Default: 5 * time.Minute}}}
+	if got := tp.forZone("shop.xn--bcher-kva.example").effective(0); got != 300 {
+		t.Errorf("unicode TTL policy key: got %d, want 300", got)
+	}
+}
+
+func TestAppendRecords_ReportsEffectiveTTL(t *testing.T) {
+	api := newFakeACME()
+	api.rrsets["_acme-challenge.example.com."] = &RRSet{
+		Name: "_acme-challenge.example.com.", Type: "TXT", TTL: 300,
+		Records: []Record{{Content: `"existing"`}},
+	}
+	p := &Provider{APIToken: apitest.Token, HTTPClient: api}
+	ctx := context.Background()
+
+	rec := libdns.TXT{Name: "_acme-challenge", Text: "new", TTL: time.Minute}
+	got, err := p.AppendRecords(ctx, "example.com.", []libdns.Record{rec})
+	if err != nil {
+		t.Fatalf("AppendRecords: %v", err)
+	}
+	if ttl := got[0].RR().TTL; ttl != 300*time.Second {
+		t.Errorf("returned TTL = %s, want existing rrset TTL 5m0s", ttl)
+	}
+
+	p.TTLPolicy = TTLPolicy{OverrideExisting: true, Max: 120 * time.Second}
+	rec.Text = "newer"
+	rec.TTL = time.Hour
+	got, err = p.AppendRecords(ctx, "example.com.", []libdns.Record{rec})
+	if err != nil {
+		t.Fatalf("AppendRecords: %v", err)
+	}
+	if ttl := got[0].RR().TTL; ttl != 120*time.Second {
+		t.Errorf("returned TTL = %s, want clamped 2m0s", ttl)
+	}
+	if ttl := api.rrsets["_acme-challenge.example.com."].TTL; ttl != 120 {
+		t.Errorf("rrset TTL = %d, want 120", ttl)
+	}
+}
@@ -0,0 +1,12 @@
+package rcodezeroacme
+
+import "github.com/kagescode/libdns-rcodezeroacme/internal/zonefmt"
+
+// parseTXT decodes TXT rdata in presentation format; see zonefmt.ParseTXT.
+func parseTXT(content string) (string, error) { return zonefmt.ParseTXT(content) }
+
+// formatTXT encodes text as TXT rdata; see zonefmt.FormatTXT.
+func formatTXT(text string) string { return zonefmt.FormatTXT(text) }
+
+// decodeTXT is parseTXT for API responses; see zonefmt.DecodeTXT.
+func decodeTXT(content string) string { return zonefmt.DecodeTXT(content) }
@@ -0,0 +1,79 @@
+package rcodezeroacme
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormatParseTXT_RoundTrip(t *testing.T) {
+	cases := []string{
+		"",
+		"short",
+		"with space",
+		`quote " and backslash \`,
+		"tab\tnewline\n",
+		"bücher ✓",
+		"\x00\xff",
+		strings.Repeat("b", 255),
+		strings.Repeat("a", 300),
+		strings.Repeat(`x"\`, 120),
+		strings.Repeat("ü", 200),
+	}
+	for _, v := range cases {
+		content := formatTXT(v)
+		for _, c := range []byte(content) {
+			if c < 0x20 || c > 0x7e {
+				t.Fatalf("formatTXT(%q) emitted non-printable byte %#x", v, c)
+			}
+		}
+		got, err := parseTXT(content)
+		if err != nil {
+			t.Fatalf("parseTXT(%q): %v", content, err)
+		}
+		if got != v {
+			t.Errorf("round trip of %d bytes: got %q, want %q", len(v), got, v)
+		}
+	}
+}
+
+func TestFormatTXT_SegmentsAt255Bytes(t *testing.T) {
+	content := formatTXT(strings.Repeat("a", 600))
+	want := `"` + strings.Repeat("a", 255) + `" "` + strings.Repeat("a", 255) + `" "` + strings.Repeat("a", 90) + `"`
+	if content != want {
+		t.Errorf("unexpected segmentation: %q", content)
+	}
+}
+
+func TestParseTXT_PresentationFormat(t *testing.T) {
+	cases := map[string]string{
+		`plain`:                 "plain",
+		`"quoted"`:              "quoted",
+		`"abc" "def"`:           "abcdef",
+		`abc def`:               "abcdef",
+		`"a\"b"  "c\\d"`:        `a"bc\d`,
+		`"with space"`:          "with space",
+		`"b\195\188cher"`:       "bücher",
+		`"\065\066" C`:          "ABC",
+		`"escaped\ space\.dot"`: "escaped space.dot",
+		`""`:                    "",
+	}
+	for in, want := range cases {
+		got, err := parseTXT(in)
+		if err != nil {
+			t.Errorf("parseTXT(%q): %v", in, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("parseTXT(%q) = %q, want %q", in, got, want)
+		}
+	}
+
+	for _, bad := range []string{`"unterminated`, `"\25"`, `"\256"`, `a"b`, `"x\`} {
+		if _, err := parseTXT(bad); err == nil {
+			t.Errorf("parseTXT(%q): expected error", bad)
+		}
+	}
+	if got := decodeTXT(`"unterminated`); got != `"unterminated` {
+		t.Errorf("decodeTXT fallback = %q", got)
+	}
+}
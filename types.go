@@ -1,6 +1,11 @@
 package rcodezeroacme
 
-import "fmt"
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/kagescode/libdns-rcodezeroacme/internal/transport"
+)
 
 type UpdateRRSet struct {
 	Name       string   `json:"name"`
@@ -21,9 +26,31 @@ type APIResponse struct {
 }
 
 func (a APIResponse) Error() string {
-	return fmt.Sprintf("%s: %s", a.Status, a.Message)
+	return fmt.Sprintf("%s: %s", a.Status, transport.Excerpt([]byte(a.Message), ""))
 }
 
+var (
+	// ErrUnauthorized matches API errors caused by a missing, invalid or
+	// insufficiently privileged token.
+	ErrUnauthorized = transport.ErrUnauthorized
+	// ErrZoneNotFound matches API errors for zones the API does not know.
+	ErrZoneNotFound = transport.ErrZoneNotFound
+	// ErrResponseTooLarge is returned when a response body exceeds the
+	// client's MaxResponseSize.
+	ErrResponseTooLarge = transport.ErrResponseTooLarge
+)
+
+// HTTPError is returned for non-2xx API responses.
+type HTTPError = transport.HTTPError
+
+// NetworkError is returned when the request could not be completed at the
+// transport level (DNS, connect, TLS, timeouts).
+type NetworkError = transport.NetworkError
+
+// NonJSONError is returned for successful responses that are not JSON,
+// such as an HTML maintenance page; it carries a sanitized excerpt.
+type NonJSONError = transport.NonJSONError
+
 type GetRRsetsResponse struct {
 	CurrentPage int     `json:"current_page"`
 	Data        []RRSet `json:"data"`
@@ -33,6 +60,53 @@ type GetRRsetsResponse struct {
 	NextPageURL *string `json:"next_page_url"`
 }
 
+type GetZonesResponse struct {
+	CurrentPage int        `json:"current_page"`
+	Data        []ZoneInfo `json:"data"`
+	LastPage    int        `json:"last_page"`
+	PerPage     int        `json:"per_page"`
+	Total       int        `json:"total"`
+	NextPageURL *string    `json:"next_page_url"`
+}
+
+type ZoneInfo struct {
+	Domain string `json:"domain"`
+	Type   string `json:"type"`
+}
+
+// ZoneDetail is a zone as returned by GET /api/v1/zones/{zone}.
+type ZoneDetail struct {
+	Domain string `json:"domain"`
+	Type   string `json:"type"`
+	Serial int64  `json:"serial"`
+
+	DNSSECStatus          string     `json:"dnssec_status"`
+	DNSSECStatusDetail    string     `json:"dnssec_status_detail"`
+	DNSSECKSKStatus       string     `json:"dnssec_ksk_status"`
+	DNSSECKSKStatusDetail string     `json:"dnssec_ksk_status_detail"`
+	DNSSECDS              stringList `json:"dnssec_ds"`
+}
+
+// stringList decodes either a JSON string or an array of strings.
+type stringList []string
+
+func (l *stringList) UnmarshalJSON(b []byte) error {
+	var one string
+	if err := json.Unmarshal(b, &one); err == nil {
+		*l = nil
+		if one != "" {
+			*l = stringList{one}
+		}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(b, &many); err != nil {
+		return err
+	}
+	*l = many
+	return nil
+}
+
 type RRSet struct {
 	Name    string   `json:"name"`
 	Type    string   `json:"type"`
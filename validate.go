@@ -0,0 +1,62 @@
+package rcodezeroacme
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// dns01DigestLen is the length of base64url(SHA-256(...)) without padding.
+const dns01DigestLen = 43
+
+// ValueValidation configures the checks TXT values must pass before
+// AppendRecords sends them. The zero value only rejects empty and
+// whitespace-only values.
+type ValueValidation struct {
+	// AllowEmpty permits empty or whitespace-only values.
+	AllowEmpty bool
+
+	// RequireDigest requires values at _acme-challenge names (including
+	// DNS-ACCOUNT-01 names) to be a standard DNS-01 digest: 43 base64url
+	// characters.
+	RequireDigest bool
+
+	// MaxLength limits values to this many bytes; zero means no limit.
+	MaxLength int
+
+	// Charset, if non-empty, lists the only characters values may contain.
+	Charset string
+}
+
+// check validates value for the record at fqdn.
+func (v ValueValidation) check(fqdn, value string) error {
+	if strings.TrimSpace(value) == "" {
+		if v.AllowEmpty {
+			return nil
+		}
+		return fmt.Errorf("invalid TXT value for %s: value is empty", fqdn)
+	}
+	if v.MaxLength > 0 && len(value) > v.MaxLength {
+		return fmt.Errorf("invalid TXT value for %s: %d bytes exceeds maximum of %d", fqdn, len(value), v.MaxLength)
+	}
+	if v.Charset != "" {
+		if i := strings.IndexFunc(value, func(r rune) bool { return !strings.ContainsRune(v.Charset, r) }); i >= 0 {
+			r, _ := utf8.DecodeRuneInString(value[i:])
+			return fmt.Errorf("invalid TXT value for %s: character %q at offset %d is not allowed", fqdn, r, i)
+		}
+	}
+	if v.RequireDigest && (isAcmeChallengeName(fqdn) || isAccountChallengeName(fqdn)) {
+		if len(value) != dns01DigestLen {
+			return fmt.Errorf("invalid TXT value for %s: DNS-01 digest must be %d base64url characters (got %d)", fqdn, dns01DigestLen, len(value))
+		}
+		if i := strings.IndexFunc(value, func(r rune) bool { return !isBase64URL(r) }); i >= 0 {
+			r, _ := utf8.DecodeRuneInString(value[i:])
+			return fmt.Errorf("invalid TXT value for %s: DNS-01 digest has non-base64url character %q at offset %d", fqdn, r, i)
+		}
+	}
+	return nil
+}
+
+func isBase64URL(r rune) bool {
+	return (r >= 'A' && r <= 'Z') || (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-' || r == '_'
+}
@@ -0,0 +1,63 @@
+package rcodezeroacme
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/libdns/libdns"
+
+	"github.com/kagescode/libdns-rcodezeroacme/internal/apitest"
+)
+
+func TestValueValidation(t *testing.T) {
+	digest := DNS01Value("token.thumb")
+	const name = "_acme-challenge.example.com."
+
+	cases := []struct {
+		v     ValueValidation
+		name  string
+		value string
+		ok    bool
+	}{
+		{ValueValidation{}, name, "anything goes", true},
+		{ValueValidation{}, name, "  ", false},
+		{ValueValidation{AllowEmpty: true}, name, "", true},
+		{ValueValidation{RequireDigest: true}, name, digest, true},
+		{ValueValidation{RequireDigest: true}, name, "short", false},
+		{ValueValidation{RequireDigest: true}, name, strings.Repeat("+", 43), false},
+		{ValueValidation{RequireDigest: true}, "_validation-persist.example.com.", "ca.example; accounturi=x", true},
+		{ValueValidation{MaxLength: 5}, name, "toolong", false},
+		{ValueValidation{Charset: "abc"}, name, "abcabc", true},
+		{ValueValidation{Charset: "abc"}, name, "abcd", false},
+	}
+	for _, c := range cases {
+		err := c.v.check(c.name, c.value)
+		if (err == nil) != c.ok {
+			t.Errorf("%+v.check(%q, %q) = %v, want ok=%v", c.v, c.name, c.value, err, c.ok)
+		}
+	}
+
+	// Multi-byte characters are reported whole, not as a broken byte.
+	err := ValueValidation{Charset: "abc"}.check(name, "abü")
+	if err == nil || !strings.Contains(err.Error(), `'ü' at offset 2`) {
+		t.Errorf("charset error = %v", err)
+	}
+}
+
+func TestAppendRecords_RejectsBeforeAPICall(t *testing.T) {
+	api := newFakeACME()
+	p := &Provider{APIToken: apitest.Token, HTTPClient: api, ValueValidation: ValueValidation{RequireDigest: true}}
+
+	recs := []libdns.Record{
+		libdns.TXT{Name: "_acme-challenge", Text: DNS01Value("a.b")},
+		libdns.TXT{Name: "_acme-challenge.www", Text: "not-a-digest"},
+	}
+	_, err := p.AppendRecords(context.Background(), "example.com.", recs)
+	if err == nil || !strings.Contains(err.Error(), "_acme-challenge.www.example.com.") {
+		t.Fatalf("expected descriptive error, got %v", err)
+	}
+	if len(api.patches) != 0 {
+		t.Errorf("no PATCH should be sent when validation fails")
+	}
+}
@@ -0,0 +1,115 @@
+package rcodezeroacme
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// VerifyStatus classifies the outcome of verifying access to one zone.
+type VerifyStatus string
+
+const (
+	VerifyOK           VerifyStatus = "ok"
+	VerifyUnauthorized VerifyStatus = "unauthorized"
+	VerifyZoneNotFound VerifyStatus = "zone_not_found"
+	VerifyNetworkError VerifyStatus = "network_error"
+	VerifyError        VerifyStatus = "error"
+)
+
+// ZoneReport is the result of verifying a single zone.
+type ZoneReport struct {
+	Zone   string
+	Status VerifyStatus
+	// Err is the underlying error; nil when Status is VerifyOK. It matches
+	// ErrUnauthorized / ErrZoneNotFound via errors.Is, or *NetworkError
+	// via errors.As, according to Status.
+	Err error
+
+	// DNSSEC is the zone's DNSSEC state and DNSSECErr the error reading it.
+	// Both are only set by Diagnose.
+	DNSSEC    *DNSSECStatus
+	DNSSECErr error
+}
+
+// Verify checks that the configured credentials can read the ACME rrsets of
+// each zone, using one small GetRRsets request per zone. If no zones are
+// given, the zones configured in Zones and ZoneTokens are checked.
+//
+// The returned error is non-nil if any zone failed; the per-zone reports
+// are returned in either case.
+func (p *Provider) Verify(ctx context.Context, zones ...string) ([]ZoneReport, error) {
+	if err := p.init(); err != nil {
+		return nil, err
+	}
+	if len(zones) == 0 {
+		// The same zone may be listed in both, spelled differently.
+		seen := map[string]bool{}
+		add := func(zone string) {
+			key := asciiKey(zone)
+			if key == "" {
+				key = normalizeName(zone)
+			}
+			if key == "" || seen[key] {
+				return
+			}
+			seen[key] = true
+			zones = append(zones, zone)
+		}
+		for _, zone := range p.Zones {
+			add(zone)
+		}
+		keys := make([]string, 0, len(p.ZoneTokens))
+		for zone := range p.ZoneTokens {
+			keys = append(keys, zone)
+		}
+		sort.Strings(keys)
+		for _, zone := range keys {
+			add(zone)
+		}
+		sort.Strings(zones)
+	}
+	if len(zones) == 0 {
+		return nil, fmt.Errorf("verify: no zones given")
+	}
+
+	reports := make([]ZoneReport, 0, len(zones))
+	var errs []error
+	for _, zone := range zones {
+		r := p.verifyZone(ctx, zone)
+		if r.Err != nil {
+			errs = append(errs, fmt.Errorf("zone %s: %s: %w", r.Zone, r.Status, r.Err))
+		}
+		reports = append(reports, r)
+	}
+	return reports, errors.Join(errs...)
+}
+
+func (p *Provider) verifyZone(ctx context.Context, zone string) ZoneReport {
+	zoneTrim, err := trimZone(zone)
+	if err != nil {
+		return ZoneReport{Zone: zone, Status: VerifyError, Err: err}
+	}
+	r := ZoneReport{Zone: zoneTrim}
+
+	_, err = p.client.GetRRsets(ctx, zoneTrim, 1, 1)
+	r.Err = err
+	r.Status = classifyError(err)
+	return r
+}
+
+func classifyError(err error) VerifyStatus {
+	var netErr *NetworkError
+	switch {
+	case err == nil:
+		return VerifyOK
+	case errors.Is(err, ErrUnauthorized):
+		return VerifyUnauthorized
+	case errors.Is(err, ErrZoneNotFound):
+		return VerifyZoneNotFound
+	case errors.As(err, &netErr), errors.Is(err, context.DeadlineExceeded):
+		return VerifyNetworkError
+	}
+	return VerifyError
+}
@@ -0,0 +1,65 @@
+package rcodezeroacme
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/kagescode/libdns-rcodezeroacme/internal/apitest"
+)
+
+// fakeHTTP answers requests with handler; it never touches the network.
+type fakeHTTP func(*http.Request) (*http.Response, error)
+
+func (f fakeHTTP) Do(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestVerify_ClassifiesPerZone(t *testing.T) {
+	hc := fakeHTTP(func(req *http.Request) (*http.Response, error) {
+		switch {
+		case strings.Contains(req.URL.Path, "/ok.example/"):
+			return apitest.JSONResponse(200, `{"data":[],"last_page":1}`), nil
+		case strings.Contains(req.URL.Path, "/denied.example/"):
+			return apitest.JSONResponse(403, `{"status":"failed","message":"forbidden"}`), nil
+		case strings.Contains(req.URL.Path, "/missing.example/"):
+			return apitest.JSONResponse(404, `{"status":"failed","message":"not found"}`), nil
+		}
+		return nil, errors.New("connection refused")
+	})
+
+	p := &Provider{APIToken: apitest.Token, HTTPClient: hc}
+	reports, err := p.Verify(context.Background(), "ok.example.", "denied.example", "missing.example", "down.example")
+	if err == nil {
+		t.Fatalf("expected aggregated error")
+	}
+
+	want := []VerifyStatus{VerifyOK, VerifyUnauthorized, VerifyZoneNotFound, VerifyNetworkError}
+	if len(reports) != len(want) {
+		t.Fatalf("got %d reports, want %d", len(reports), len(want))
+	}
+	for i, r := range reports {
+		if r.Status != want[i] {
+			t.Errorf("%s: got %s, want %s (err=%v)", r.Zone, r.Status, want[i], r.Err)
+		}
+	}
+	if !errors.Is(reports[1].Err, ErrUnauthorized) {
+		t.Errorf("expected ErrUnauthorized, got %v", reports[1].Err)
+	}
+}
+
+func TestVerify_DeduplicatesConfiguredZones(t *testing.T) {
+	hc := fakeHTTP(func(req *http.Request) (*http.Response, error) {
+		return apitest.JSONResponse(200, `{"data":[],"last_page":1}`), nil
+	})
+	p := &Provider{
+		APIToken:   apitest.Token,
+		HTTPClient: hc,
+		Zones:      []string{"example.com.", "bücher.example"},
+		ZoneTokens: map[string]string{"Example.com": "rcz-zone-token-one", "xn--bcher-kva.example.": "rcz-zone-token-two"},
+	}
+	reports, err := p.Verify(context.Background())
+	if err != nil || len(reports) != 2 {
+		t.Fatalf("Verify = %+v, %v; want one report per zone", reports, err)
+	}
+}
@@ -0,0 +1,93 @@
+package rcodezeroacme
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/libdns/libdns"
+)
+
+// ListZones implements libdns.ZoneLister. It lists the zones visible to the
+// API token. If the token may not list zones, it instead probes the zones
+// from Zones and ZoneTokens and returns those it can read ACME rrsets for.
+func (p *Provider) ListZones(ctx context.Context) ([]libdns.Zone, error) {
+	if err := p.init(); err != nil {
+		return nil, err
+	}
+
+	zones, err := p.listZones(ctx)
+	if errors.Is(err, ErrUnauthorized) || errors.Is(err, ErrNoToken) {
+		if probed, ok, err := p.probeZones(ctx); ok {
+			return probed, err
+		}
+	}
+	return zones, err
+}
+
+func (p *Provider) listZones(ctx context.Context) ([]libdns.Zone, error) {
+	var out []libdns.Zone
+	page, pageSize := 1, 100
+
+	for {
+		resp, err := p.client.GetZones(ctx, page, pageSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, z := range resp.Data {
+			name := strings.TrimSuffix(strings.TrimSpace(z.Domain), ".")
+			if name == "" {
+				continue
+			}
+			out = append(out, libdns.Zone{Name: name + "."})
+		}
+
+		if resp.LastPage <= page || resp.LastPage == 0 {
+			break
+		}
+		page++
+	}
+
+	return out, nil
+}
+
+// probeZones returns the configured zones whose ACME rrsets can be read.
+// Zones the token may not read or that don't exist are left out; other
+// errors, such as network failures, are returned. ok is false if no zones
+// are configured.
+func (p *Provider) probeZones(ctx context.Context) (zones []libdns.Zone, ok bool, err error) {
+	// Keyed by ASCII name, so Unicode and punycode spellings of a zone are
+	// probed once.
+	candidates := map[string]bool{}
+	for _, z := range p.Zones {
+		candidates[asciiKey(z)] = true
+	}
+	for z := range p.ZoneTokens {
+		candidates[asciiKey(z)] = true
+	}
+	delete(candidates, "")
+	if len(candidates) == 0 {
+		return nil, false, nil
+	}
+
+	names := make([]string, 0, len(candidates))
+	for z := range candidates {
+		names = append(names, z)
+	}
+	sort.Strings(names)
+
+	zones = []libdns.Zone{}
+	for _, z := range names {
+		_, err := p.client.GetRRsets(ctx, z, 1, 1)
+		switch {
+		case errors.Is(err, ErrUnauthorized) || errors.Is(err, ErrZoneNotFound):
+			continue
+		case err != nil:
+			return nil, true, fmt.Errorf("probe zone %s: %w", z, err)
+		}
+		zones = append(zones, libdns.Zone{Name: z + "."})
+	}
+	return zones, true, nil
+}
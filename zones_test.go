@@ -0,0 +1,99 @@
+package rcodezeroacme
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/kagescode/libdns-rcodezeroacme/internal/apitest"
+)
+
+func TestListZones_Paginates(t *testing.T) {
+	hc := fakeHTTP(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Path != "/api/v1/zones" {
+			t.Fatalf("unexpected path %s", req.URL.Path)
+		}
+		if req.URL.Query().Get("page") == "2" {
+			return apitest.JSONResponse(200, `{"current_page":2,"last_page":2,"data":[{"domain":"b.example"}]}`), nil
+		}
+		return apitest.JSONResponse(200, `{"current_page":1,"last_page":2,"data":[{"domain":"a.example"}]}`), nil
+	})
+
+	p := &Provider{APIToken: apitest.Token, HTTPClient: hc}
+	zones, err := p.ListZones(context.Background())
+	if err != nil {
+		t.Fatalf("ListZones: %v", err)
+	}
+	if len(zones) != 2 || zones[0].Name != "a.example." || zones[1].Name != "b.example." {
+		t.Fatalf("zones = %+v", zones)
+	}
+}
+
+func TestListZones_FallsBackToProbing(t *testing.T) {
+	hc := fakeHTTP(func(req *http.Request) (*http.Response, error) {
+		switch {
+		case req.URL.Path == "/api/v1/zones":
+			return apitest.JSONResponse(403, `{"status":"failed","message":"forbidden"}`), nil
+		case strings.Contains(req.URL.Path, "/zones/ok.example/"):
+			return apitest.JSONResponse(200, `{"data":[],"last_page":1}`), nil
+		}
+		return apitest.JSONResponse(403, `{"status":"failed","message":"forbidden"}`), nil
+	})
+
+	p := &Provider{
+		HTTPClient: hc,
+		Zones:      []string{"ok.example."},
+		ZoneTokens: map[string]string{"denied.example": "t2", "ok.example": "t1"},
+	}
+	zones, err := p.ListZones(context.Background())
+	if err != nil {
+		t.Fatalf("ListZones: %v", err)
+	}
+	if len(zones) != 1 || zones[0].Name != "ok.example." {
+		t.Fatalf("zones = %+v", zones)
+	}
+
+	p = &Provider{APIToken: apitest.Token, HTTPClient: hc}
+	if _, err := p.ListZones(context.Background()); err == nil {
+		t.Errorf("expected error without configured zones")
+	}
+}
+
+func TestListZones_ProbingDedupesAndReportsFailures(t *testing.T) {
+	var probes []string
+	down := false
+	hc := fakeHTTP(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Path == "/api/v1/zones" {
+			return apitest.JSONResponse(403, `{"status":"failed","message":"forbidden"}`), nil
+		}
+		if down {
+			return nil, errors.New("connection refused")
+		}
+		probes = append(probes, req.URL.Path)
+		if strings.Contains(req.URL.Path, "/gone.example/") {
+			return apitest.JSONResponse(404, `{"status":"failed","message":"not found"}`), nil
+		}
+		return apitest.JSONResponse(200, `{"data":[],"last_page":1}`), nil
+	})
+
+	p := &Provider{
+		APIToken:   apitest.Token,
+		HTTPClient: hc,
+		Zones:      []string{"bücher.example", "gone.example"},
+		ZoneTokens: map[string]string{"xn--bcher-kva.example.": "rcz-zone-token-one"},
+	}
+	zones, err := p.ListZones(context.Background())
+	if err != nil || len(zones) != 1 || zones[0].Name != "xn--bcher-kva.example." {
+		t.Fatalf("ListZones = %+v, %v", zones, err)
+	}
+	if len(probes) != 2 {
+		t.Errorf("probed %q, want each zone once", probes)
+	}
+
+	down = true
+	if zones, err := p.ListZones(context.Background()); err == nil {
+		t.Errorf("ListZones with the network down = %+v, want an error", zones)
+	}
+}